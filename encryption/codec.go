@@ -0,0 +1,115 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec 对象序列化方式,用于 EncryptObjectEncoded/DecryptObjectEncoded
+type Codec interface {
+	// Name 返回序列化格式名称
+	Name() string
+	// Marshal 将对象序列化为字节
+	Marshal(v any) ([]byte, error)
+	// Unmarshal 将字节反序列化到对象,v为指针
+	Unmarshal(data []byte, v any) error
+}
+
+// ErrCodecUnsupportedType 对象类型不受序列化方式支持,如 ProtobufCodec 要求 proto.Message
+var ErrCodecUnsupportedType = errors.New("encryption: type not supported by codec")
+
+var (
+	// JSONCodec encoding/json,EncryptObject/DecryptObject 使用的默认格式
+	JSONCodec Codec = jsonCodec{}
+	// GobCodec encoding/gob,仅适用于Go服务之间
+	GobCodec Codec = gobCodec{}
+	// ProtobufCodec Protocol Buffers二进制格式,对象须实现 proto.Message
+	ProtobufCodec Codec = protobufCodec{}
+	// MsgpackCodec MessagePack格式,字段名取 codec 或 json 标签
+	MsgpackCodec Codec = newMsgpackCodec()
+	// CBORCodec CBOR(RFC 8949)格式,字段名取 codec 或 json 标签
+	CBORCodec Codec = &ugorjiCodec{name: "cbor", handle: &codec.CborHandle{}}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                       { return "json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type gobCodec struct{}
+
+func (gobCodec) Name() string { return "gob" }
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string { return "protobuf" }
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T does not implement proto.Message", ErrCodecUnsupportedType, v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T does not implement proto.Message", ErrCodecUnsupportedType, v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// ugorjiCodec 基于 github.com/ugorji/go/codec 的格式,handle 配置后可并发使用
+type ugorjiCodec struct {
+	name   string
+	handle codec.Handle
+}
+
+// newMsgpackCodec 使用新版规范的str/bin类型,字符串解码为string而非[]byte
+func newMsgpackCodec() *ugorjiCodec {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.RawToString = true
+	return &ugorjiCodec{name: "msgpack", handle: h}
+}
+
+func (c *ugorjiCodec) Name() string { return c.name }
+
+func (c *ugorjiCodec) Marshal(v any) ([]byte, error) {
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, c.handle).Encode(v); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ugorjiCodec) Unmarshal(data []byte, v any) error {
+	return codec.NewDecoderBytes(data, c.handle).Decode(v)
+}
+
+// codecOrDefault 未指定序列化方式时使用 JSONCodec
+func codecOrDefault(c Codec) Codec {
+	if c == nil {
+		return JSONCodec
+	}
+	return c
+}
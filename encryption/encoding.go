@@ -0,0 +1,75 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Encoding 密文的文本编码方式,用于 EncryptEncoded/DecryptEncoded
+type Encoding int
+
+const (
+	// EncodingHex 16进制,编码时输出小写,解码时不区分大小写
+	EncodingHex Encoding = iota
+	// EncodingBase64 标准Base64(RFC 4648),带=填充
+	EncodingBase64
+	// EncodingBase64URL URL安全Base64(RFC 4648 §5),不带=填充,可直接用于URL、文件名及Cookie
+	EncodingBase64URL
+	// EncodingRaw 不编码,字符串即原始字节
+	EncodingRaw
+)
+
+// ErrUnsupportedEncoding 编码方式不受支持
+var ErrUnsupportedEncoding = errors.New("encryption: unsupported encoding")
+
+// String 返回编码名称
+func (e Encoding) String() string {
+	switch e {
+	case EncodingHex:
+		return "hex"
+	case EncodingBase64:
+		return "base64"
+	case EncodingBase64URL:
+		return "base64url"
+	case EncodingRaw:
+		return "raw"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+}
+
+// Encode 将数据编码为字符串
+// data 待编码数据
+func (e Encoding) Encode(data []byte) (string, error) {
+	switch e {
+	case EncodingHex:
+		return hex.EncodeToString(data), nil
+	case EncodingBase64:
+		return base64.StdEncoding.EncodeToString(data), nil
+	case EncodingBase64URL:
+		return base64.RawURLEncoding.EncodeToString(data), nil
+	case EncodingRaw:
+		return string(data), nil
+	default:
+		return "", fmt.Errorf("%w: %v", ErrUnsupportedEncoding, e)
+	}
+}
+
+// Decode 将字符串解码为数据
+// s 待解码字符串
+func (e Encoding) Decode(s string) ([]byte, error) {
+	switch e {
+	case EncodingHex:
+		return hex.DecodeString(s)
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(s)
+	case EncodingBase64URL:
+		return base64.RawURLEncoding.DecodeString(s)
+	case EncodingRaw:
+		return []byte(s), nil
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedEncoding, e)
+	}
+}
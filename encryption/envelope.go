@@ -0,0 +1,183 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/tjfoc/gmsm/sm3"
+)
+
+const (
+	// EnvelopeVersion 当前数字信封格式版本
+	// 版本2起MAC覆盖版本、算法、IV及全部接收者,版本1的信封不再受支持
+	EnvelopeVersion = 2
+	// EnvelopeAlgorithm 数据加密算法: SM4-CBC 加密后以 HMAC-SM3 认证(Encrypt-then-MAC)
+	EnvelopeAlgorithm = "SM4-CBC-HMAC-SM3"
+	// EnvelopeKeyWrap 数据密钥封装算法: SM2 公钥加密,密文格式 C1C3C2
+	EnvelopeKeyWrap = "SM2-C1C3C2"
+
+	envelopeKeySize = 16
+)
+
+var (
+	// ErrInvalidEnvelope 信封结构错误或版本、算法不受支持
+	ErrInvalidEnvelope = errors.New("envelope: invalid envelope")
+	// ErrNoRecipient 信封中没有与当前私钥对应的接收者
+	ErrNoRecipient = errors.New("envelope: no matching recipient")
+	// ErrEnvelopeAuth 信封数据完整性校验失败
+	ErrEnvelopeAuth = errors.New("envelope: authentication failed")
+)
+
+// Envelope SM2+SM4 数字信封
+// 随机生成的数据密钥(SM4密钥||HMAC密钥)分别用每个接收者的SM2公钥加密
+type Envelope struct {
+	Version    int                 `json:"version"`
+	Algorithm  string              `json:"algorithm"`
+	KeyWrap    string              `json:"keyWrap"`
+	IV         []byte              `json:"iv"`
+	Recipients []EnvelopeRecipient `json:"recipients"`
+	Ciphertext []byte              `json:"ciphertext"`
+	MAC        []byte              `json:"mac"`
+}
+
+// EnvelopeRecipient 信封接收者
+type EnvelopeRecipient struct {
+	// KeyID 接收者公钥标识,见 (*SM2).KeyID
+	KeyID string `json:"keyId"`
+	// EncryptedKey SM2加密后的数据密钥
+	EncryptedKey []byte `json:"encryptedKey"`
+}
+
+// KeyID 公钥标识,取非压缩公钥 04||X||Y 的SM3摘要16进制字符串,未持有公钥时返回空字符串
+func (enc *SM2) KeyID() string {
+	if enc == nil || enc.publicKey == nil {
+		return ""
+	}
+	return hex.EncodeToString(sm3.Sm3Sum(EncodePublicKey(enc.publicKey)))
+}
+
+// SealEnvelope 生成数字信封并序列化为JSON
+// plaintext 待加密数据
+// recipients 接收者,只需持有公钥
+func SealEnvelope(plaintext []byte, recipients ...*SM2) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("%w: at least one recipient required", ErrInvalidEnvelope)
+	}
+	for i, recipient := range recipients {
+		if recipient == nil || recipient.publicKey == nil {
+			return nil, fmt.Errorf("%w: recipient %d", ErrPublicKeyRequired, i)
+		}
+	}
+	dataKey := make([]byte, 2*envelopeKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	iv := make([]byte, envelopeKeySize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	encKey, macKey := dataKey[:envelopeKeySize], dataKey[envelopeKeySize:]
+
+	sm4e, err := NewSM4(encKey, iv)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := sm4e.EncryptBytes(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := Envelope{
+		Version:    EnvelopeVersion,
+		Algorithm:  EnvelopeAlgorithm,
+		KeyWrap:    EnvelopeKeyWrap,
+		IV:         iv,
+		Ciphertext: ciphertext,
+	}
+	for _, recipient := range recipients {
+		encryptedKey, err := recipient.EncryptBytes(dataKey, CipherC1C3C2)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Recipients = append(envelope.Recipients, EnvelopeRecipient{
+			KeyID:        recipient.KeyID(),
+			EncryptedKey: encryptedKey,
+		})
+	}
+	envelope.MAC = envelopeMAC(macKey, &envelope)
+	return json.Marshal(envelope)
+}
+
+// OpenEnvelope 使用接收者私钥打开数字信封
+// envelope SealEnvelope 生成的信封
+// recipient 接收者,需持有私钥
+func OpenEnvelope(envelope []byte, recipient *SM2) ([]byte, error) {
+	if recipient == nil || recipient.privateKey == nil {
+		return nil, ErrPrivateKeyRequired
+	}
+	var env Envelope
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	if env.Version != EnvelopeVersion || env.Algorithm != EnvelopeAlgorithm || env.KeyWrap != EnvelopeKeyWrap {
+		return nil, fmt.Errorf("%w: unsupported version %d or algorithm %s/%s",
+			ErrInvalidEnvelope, env.Version, env.Algorithm, env.KeyWrap)
+	}
+	if len(env.IV) != envelopeKeySize {
+		return nil, fmt.Errorf("%w: iv must be %d bytes", ErrInvalidEnvelope, envelopeKeySize)
+	}
+
+	keyID := recipient.KeyID()
+	for _, r := range env.Recipients {
+		if r.KeyID != keyID {
+			continue
+		}
+		dataKey, err := recipient.Decrypt(r.EncryptedKey, CipherC1C3C2)
+		if err != nil {
+			return nil, err
+		}
+		if len(dataKey) != 2*envelopeKeySize {
+			return nil, fmt.Errorf("%w: data key must be %d bytes", ErrInvalidEnvelope, 2*envelopeKeySize)
+		}
+		encKey, macKey := dataKey[:envelopeKeySize], dataKey[envelopeKeySize:]
+		if !hmac.Equal(env.MAC, envelopeMAC(macKey, &env)) {
+			return nil, ErrEnvelopeAuth
+		}
+		sm4e, err := NewSM4(encKey, env.IV)
+		if err != nil {
+			return nil, err
+		}
+		return sm4e.Decrypt(env.Ciphertext)
+	}
+	return nil, ErrNoRecipient
+}
+
+// envelopeMAC 计算信封除MAC外全部字段的 HMAC-SM3
+// 依次写入 version、algorithm、keyWrap、iv、接收者个数及每个接收者的 keyId、encryptedKey、ciphertext,
+// 版本和个数为4字节大端整数,其余字段前加4字节大端长度,避免字段边界歧义
+func envelopeMAC(macKey []byte, env *Envelope) []byte {
+	mac := NewHMACSM3(macKey)
+	writeUint32 := func(v int) {
+		mac.Write(binary.BigEndian.AppendUint32(nil, uint32(v)))
+	}
+	writeField := func(b []byte) {
+		writeUint32(len(b))
+		mac.Write(b)
+	}
+	writeUint32(env.Version)
+	writeField([]byte(env.Algorithm))
+	writeField([]byte(env.KeyWrap))
+	writeField(env.IV)
+	writeUint32(len(env.Recipients))
+	for _, r := range env.Recipients {
+		writeField([]byte(r.KeyID))
+		writeField(r.EncryptedKey)
+	}
+	writeField(env.Ciphertext)
+	return mac.Sum(nil)
+}
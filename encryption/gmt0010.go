@@ -0,0 +1,562 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/tjfoc/gmsm/sm3"
+	"github.com/tjfoc/gmsm/x509"
+)
+
+// GM/T 0010 内容类型及 GM/T 0006 算法标识
+var (
+	OIDGMData                   = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 1}
+	OIDGMSignedData             = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 2}
+	OIDGMEnvelopedData          = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 3}
+	OIDGMSignedAndEnvelopedData = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 4}
+
+	oidSM3         = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401}
+	oidSM2Sign     = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301, 1}
+	oidSM2Encrypt  = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301, 3}
+	oidSM3WithSM2  = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}
+	oidSM4CBC      = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 104, 2}
+	oidECPublicKey = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+	// Tongsuo 等实现在国密算法下沿用 PKCS#7 内容类型,解析时一并接受
+	oidPKCS7Data                   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS7SignedData             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidPKCS7EnvelopedData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidPKCS7SignedAndEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 4}
+	oidAttributeMessageDigest      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+)
+
+var (
+	// ErrInvalidPKCS7 数据不是合法的 GM/T 0010 结构或使用了不支持的算法
+	ErrInvalidPKCS7 = errors.New("pkcs7: invalid GM/T 0010 structure")
+	// ErrPKCS7SignerNotFound 找不到签名者证书
+	ErrPKCS7SignerNotFound = errors.New("pkcs7: signer certificate not found")
+	// ErrPKCS7NotRecipient 当前证书不在接收者列表中
+	ErrPKCS7NotRecipient = errors.New("pkcs7: no matching recipient")
+)
+
+// PKCS7Options GM/T 0010 签名数据编码选项
+type PKCS7Options struct {
+	// Detached 分离式签名,签名数据中不包含原文
+	Detached bool
+	// Certificates 额外嵌入的证书,如中间CA证书
+	Certificates []*x509.Certificate
+	// OmitSignerCertificate 不嵌入签名者证书,验签方需自行提供
+	OmitSignerCertificate bool
+}
+
+// SignedData 解析后的 GM/T 0010 签名数据
+type SignedData struct {
+	// Content 原文,分离式签名时为空
+	Content []byte
+	// Certificates 嵌入的证书,验签前可追加签名者证书
+	Certificates []*x509.Certificate
+	signers      []pkcs7SignerInfo
+}
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type pkcs7IssuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type pkcs7SignerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     pkcs7IssuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue     `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue     `asn1:"optional,tag:1"`
+	SignerInfos      []pkcs7SignerInfo `asn1:"set"`
+}
+
+type pkcs7RecipientInfo struct {
+	Version                int
+	IssuerAndSerialNumber  pkcs7IssuerAndSerial
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type pkcs7EncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"optional,tag:0"`
+}
+
+type pkcs7EnvelopedData struct {
+	Version              int
+	RecipientInfos       []pkcs7RecipientInfo `asn1:"set"`
+	EncryptedContentInfo pkcs7EncryptedContentInfo
+}
+
+type pkcs7SignedAndEnvelopedData struct {
+	Version              int
+	RecipientInfos       []pkcs7RecipientInfo       `asn1:"set"`
+	DigestAlgorithms     []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncryptedContentInfo pkcs7EncryptedContentInfo
+	Certificates         asn1.RawValue     `asn1:"optional,tag:0"`
+	CRLs                 asn1.RawValue     `asn1:"optional,tag:1"`
+	SignerInfos          []pkcs7SignerInfo `asn1:"set"`
+}
+
+type pkcs7Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// EncodeSignedData 生成 GM/T 0010 签名数据(SignedData),签名算法为SM2/SM3
+// content 原文
+// signer 签名者,需持有私钥
+// signerCert 签名者证书
+// opts 编码选项,可为nil
+func EncodeSignedData(content []byte, signer *SM2, signerCert *x509.Certificate, opts *PKCS7Options) ([]byte, error) {
+	if opts == nil {
+		opts = &PKCS7Options{}
+	}
+	signerInfo, err := newPKCS7SignerInfo(content, signer, signerCert)
+	if err != nil {
+		return nil, err
+	}
+	sd := pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidSM3}},
+		ContentInfo:      pkcs7ContentInfo{ContentType: OIDGMData},
+		Certificates:     marshalPKCS7Certificates(signerCert, opts),
+		SignerInfos:      []pkcs7SignerInfo{*signerInfo},
+	}
+	if !opts.Detached {
+		sd.ContentInfo.Content, err = explicitOctetString(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return marshalPKCS7ContentInfo(OIDGMSignedData, sd)
+}
+
+// DecodeSignedData 解析 GM/T 0010 签名数据,解析后需调用 VerifyWithRoots 等方法验签
+func DecodeSignedData(der []byte) (*SignedData, error) {
+	var sd pkcs7SignedData
+	if err := unmarshalPKCS7ContentInfo(der, &sd, OIDGMSignedData, oidPKCS7SignedData); err != nil {
+		return nil, err
+	}
+	result := &SignedData{signers: sd.SignerInfos}
+	if len(sd.ContentInfo.Content.Bytes) != 0 {
+		if _, err := asn1.Unmarshal(sd.ContentInfo.Content.Bytes, &result.Content); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPKCS7, err)
+		}
+	}
+	certificates, err := parsePKCS7Certificates(sd.Certificates)
+	if err != nil {
+		return nil, err
+	}
+	result.Certificates = certificates
+	return result, nil
+}
+
+// Verify 使用嵌入的证书验证所有签名者对原文的签名,返回签名者证书
+// 只证明签名与消息中的证书匹配,任何人都能用自签名证书生成通过验证的消息;
+// 调用方须自行检查返回的签名者证书,或使用 VerifyWithRoots 校验证书链
+func (sd *SignedData) Verify() ([]*x509.Certificate, error) {
+	return sd.VerifyDetached(sd.Content)
+}
+
+// VerifyDetached 验证分离式签名,返回签名者证书;与 Verify 一样不校验签名者是否可信
+// content 原文
+func (sd *SignedData) VerifyDetached(content []byte) ([]*x509.Certificate, error) {
+	if len(sd.signers) == 0 {
+		return nil, fmt.Errorf("%w: no signer", ErrInvalidPKCS7)
+	}
+	signers := make([]*x509.Certificate, 0, len(sd.signers))
+	for i := range sd.signers {
+		signerCert, err := verifyPKCS7SignerInfo(&sd.signers[i], content, sd.Certificates)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signerCert)
+	}
+	return signers, nil
+}
+
+// VerifyWithRoots 验证签名,并校验每个签名者证书能经嵌入的证书链接到受信任的根证书
+// 签名者证书不可信时返回 ErrCertificateVerify
+// roots 受信任的根证书
+func (sd *SignedData) VerifyWithRoots(roots []*x509.Certificate) ([]*x509.Certificate, error) {
+	return sd.VerifyDetachedWithRoots(sd.Content, roots)
+}
+
+// VerifyDetachedWithRoots 验证分离式签名并校验签名者证书链,见 VerifyWithRoots
+// content 原文
+// roots 受信任的根证书
+func (sd *SignedData) VerifyDetachedWithRoots(content []byte, roots []*x509.Certificate) ([]*x509.Certificate, error) {
+	signers, err := sd.VerifyDetached(content)
+	if err != nil {
+		return nil, err
+	}
+	for _, signerCert := range signers {
+		if _, err := VerifyCertificateChain(signerCert, sd.Certificates, roots); err != nil {
+			return nil, err
+		}
+	}
+	return signers, nil
+}
+
+// EncodeEnvelopedData 生成 GM/T 0010 数字信封(EnvelopedData)
+// 内容使用随机SM4-CBC密钥加密,密钥使用接收者证书中的SM2公钥加密
+// content 原文
+// recipients 接收者证书
+func EncodeEnvelopedData(content []byte, recipients ...*x509.Certificate) ([]byte, error) {
+	recipientInfos, encryptedContentInfo, err := newPKCS7Envelope(content, recipients)
+	if err != nil {
+		return nil, err
+	}
+	ed := pkcs7EnvelopedData{
+		Version:              0,
+		RecipientInfos:       recipientInfos,
+		EncryptedContentInfo: *encryptedContentInfo,
+	}
+	return marshalPKCS7ContentInfo(OIDGMEnvelopedData, ed)
+}
+
+// DecodeEnvelopedData 解析并解密 GM/T 0010 数字信封
+// der 数字信封
+// recipient 接收者,需持有私钥
+// recipientCert 接收者证书,为nil时依次尝试所有接收者
+func DecodeEnvelopedData(der []byte, recipient *SM2, recipientCert *x509.Certificate) ([]byte, error) {
+	var ed pkcs7EnvelopedData
+	if err := unmarshalPKCS7ContentInfo(der, &ed, OIDGMEnvelopedData, oidPKCS7EnvelopedData); err != nil {
+		return nil, err
+	}
+	return openPKCS7Envelope(ed.RecipientInfos, &ed.EncryptedContentInfo, recipient, recipientCert)
+}
+
+// EncodeSignedAndEnvelopedData 生成 GM/T 0010 带签名的数字信封(SignedAndEnvelopedData)
+// 签名针对原文计算,原文使用SM4-CBC加密
+// content 原文
+// signer 签名者,需持有私钥
+// signerCert 签名者证书,嵌入结果中
+// recipients 接收者证书
+func EncodeSignedAndEnvelopedData(content []byte, signer *SM2, signerCert *x509.Certificate, recipients ...*x509.Certificate) ([]byte, error) {
+	signerInfo, err := newPKCS7SignerInfo(content, signer, signerCert)
+	if err != nil {
+		return nil, err
+	}
+	recipientInfos, encryptedContentInfo, err := newPKCS7Envelope(content, recipients)
+	if err != nil {
+		return nil, err
+	}
+	sed := pkcs7SignedAndEnvelopedData{
+		Version:              1,
+		RecipientInfos:       recipientInfos,
+		DigestAlgorithms:     []pkix.AlgorithmIdentifier{{Algorithm: oidSM3}},
+		EncryptedContentInfo: *encryptedContentInfo,
+		Certificates:         marshalPKCS7Certificates(signerCert, &PKCS7Options{}),
+		SignerInfos:          []pkcs7SignerInfo{*signerInfo},
+	}
+	return marshalPKCS7ContentInfo(OIDGMSignedAndEnvelopedData, sed)
+}
+
+// DecodeSignedAndEnvelopedData 解析并解密 GM/T 0010 带签名的数字信封
+// 返回的 SignedData 中 Content 为解密后的原文,需调用 VerifyWithRoots 或 Verify 验签
+// der 带签名的数字信封
+// recipient 接收者,需持有私钥
+// recipientCert 接收者证书,为nil时依次尝试所有接收者
+func DecodeSignedAndEnvelopedData(der []byte, recipient *SM2, recipientCert *x509.Certificate) (*SignedData, error) {
+	var sed pkcs7SignedAndEnvelopedData
+	if err := unmarshalPKCS7ContentInfo(der, &sed, OIDGMSignedAndEnvelopedData, oidPKCS7SignedAndEnvelopedData); err != nil {
+		return nil, err
+	}
+	content, err := openPKCS7Envelope(sed.RecipientInfos, &sed.EncryptedContentInfo, recipient, recipientCert)
+	if err != nil {
+		return nil, err
+	}
+	certificates, err := parsePKCS7Certificates(sed.Certificates)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedData{Content: content, Certificates: certificates, signers: sed.SignerInfos}, nil
+}
+
+// newPKCS7SignerInfo 对原文直接做SM2签名(含ZA),不带认证属性
+func newPKCS7SignerInfo(content []byte, signer *SM2, signerCert *x509.Certificate) (*pkcs7SignerInfo, error) {
+	if signerCert == nil {
+		return nil, fmt.Errorf("%w: signer certificate required", ErrInvalidPKCS7)
+	}
+	signature, err := signer.Sign(content, SignatureASN1)
+	if err != nil {
+		return nil, err
+	}
+	return &pkcs7SignerInfo{
+		Version:                   1,
+		IssuerAndSerialNumber:     pkcs7IssuerAndSerialOf(signerCert),
+		DigestAlgorithm:           pkix.AlgorithmIdentifier{Algorithm: oidSM3},
+		DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSM2Sign},
+		EncryptedDigest:           signature,
+	}, nil
+}
+
+// verifyPKCS7SignerInfo 验证单个签名者
+// 带认证属性时(如Tongsuo输出)先比对 messageDigest,再验证对属性集合的签名
+func verifyPKCS7SignerInfo(signerInfo *pkcs7SignerInfo, content []byte, certificates []*x509.Certificate) (*x509.Certificate, error) {
+	if !signerInfo.DigestAlgorithm.Algorithm.Equal(oidSM3) {
+		return nil, fmt.Errorf("%w: unsupported digest algorithm %v", ErrInvalidPKCS7, signerInfo.DigestAlgorithm.Algorithm)
+	}
+	alg := signerInfo.DigestEncryptionAlgorithm.Algorithm
+	if !alg.Equal(oidSM2Sign) && !alg.Equal(oidSM3WithSM2) && !alg.Equal(oidECPublicKey) {
+		return nil, fmt.Errorf("%w: unsupported signature algorithm %v", ErrInvalidPKCS7, alg)
+	}
+	var signerCert *x509.Certificate
+	for _, cert := range certificates {
+		if pkcs7IssuerAndSerialMatches(&signerInfo.IssuerAndSerialNumber, cert) {
+			signerCert = cert
+			break
+		}
+	}
+	if signerCert == nil {
+		return nil, ErrPKCS7SignerNotFound
+	}
+	verifier, err := NewSM2FromCertificate(signerCert)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := content
+	if len(signerInfo.AuthenticatedAttributes.FullBytes) != 0 {
+		digest, err := pkcs7MessageDigest(signerInfo.AuthenticatedAttributes.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(digest, sm3.Sm3Sum(content)) {
+			return nil, ErrInvalidSignature
+		}
+		// 认证属性以 SET OF 的通用标签参与签名,而非 [0] IMPLICIT
+		signed = append([]byte{0x31}, signerInfo.AuthenticatedAttributes.FullBytes[1:]...)
+	}
+	if err := verifier.Verify(signed, signerInfo.EncryptedDigest, SignatureASN1); err != nil {
+		return nil, err
+	}
+	return signerCert, nil
+}
+
+// pkcs7MessageDigest 取出认证属性中的 messageDigest
+func pkcs7MessageDigest(attributes []byte) ([]byte, error) {
+	for len(attributes) > 0 {
+		var attr pkcs7Attribute
+		rest, err := asn1.Unmarshal(attributes, &attr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPKCS7, err)
+		}
+		attributes = rest
+		if !attr.Type.Equal(oidAttributeMessageDigest) {
+			continue
+		}
+		var digest []byte
+		if _, err := asn1.Unmarshal(attr.Values.Bytes, &digest); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPKCS7, err)
+		}
+		return digest, nil
+	}
+	return nil, fmt.Errorf("%w: messageDigest attribute missing", ErrInvalidPKCS7)
+}
+
+// newPKCS7Envelope 使用随机SM4密钥加密原文,并为每个接收者封装密钥
+func newPKCS7Envelope(content []byte, recipients []*x509.Certificate) ([]pkcs7RecipientInfo, *pkcs7EncryptedContentInfo, error) {
+	if len(recipients) == 0 {
+		return nil, nil, fmt.Errorf("%w: at least one recipient required", ErrInvalidPKCS7)
+	}
+	key := make([]byte, envelopeKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	iv := make([]byte, envelopeKeySize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, nil, err
+	}
+	sm4e, err := NewSM4(key, iv)
+	if err != nil {
+		return nil, nil, err
+	}
+	encryptedContent, err := sm4e.EncryptBytes(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recipientInfos := make([]pkcs7RecipientInfo, 0, len(recipients))
+	for _, cert := range recipients {
+		encryptor, err := NewSM2FromCertificate(cert)
+		if err != nil {
+			return nil, nil, err
+		}
+		encryptedKey, err := encryptor.EncryptBytes(key, CipherASN1)
+		if err != nil {
+			return nil, nil, err
+		}
+		recipientInfos = append(recipientInfos, pkcs7RecipientInfo{
+			Version:                0,
+			IssuerAndSerialNumber:  pkcs7IssuerAndSerialOf(cert),
+			KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSM2Encrypt},
+			EncryptedKey:           encryptedKey,
+		})
+	}
+	encryptedContentInfo := &pkcs7EncryptedContentInfo{
+		ContentType: OIDGMData,
+		ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidSM4CBC,
+			Parameters: asn1.RawValue{FullBytes: ivParam},
+		},
+		EncryptedContent: encryptedContent,
+	}
+	return recipientInfos, encryptedContentInfo, nil
+}
+
+// openPKCS7Envelope 解封数据密钥并解密原文
+func openPKCS7Envelope(recipientInfos []pkcs7RecipientInfo, eci *pkcs7EncryptedContentInfo, recipient *SM2, recipientCert *x509.Certificate) ([]byte, error) {
+	if recipient == nil || recipient.privateKey == nil {
+		return nil, ErrPrivateKeyRequired
+	}
+	if !eci.ContentEncryptionAlgorithm.Algorithm.Equal(oidSM4CBC) {
+		return nil, fmt.Errorf("%w: unsupported content encryption algorithm %v", ErrInvalidPKCS7, eci.ContentEncryptionAlgorithm.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(eci.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv); err != nil || len(iv) != envelopeKeySize {
+		return nil, fmt.Errorf("%w: invalid SM4-CBC iv", ErrInvalidPKCS7)
+	}
+	if len(eci.EncryptedContent) == 0 || len(eci.EncryptedContent)%envelopeKeySize != 0 {
+		return nil, fmt.Errorf("%w: invalid encrypted content length", ErrInvalidPKCS7)
+	}
+
+	var key []byte
+	for _, ri := range recipientInfos {
+		if recipientCert != nil && !pkcs7IssuerAndSerialMatches(&ri.IssuerAndSerialNumber, recipientCert) {
+			continue
+		}
+		if !ri.KeyEncryptionAlgorithm.Algorithm.Equal(oidSM2Encrypt) && !ri.KeyEncryptionAlgorithm.Algorithm.Equal(oidECPublicKey) {
+			continue
+		}
+		decrypted, err := recipient.Decrypt(ri.EncryptedKey, CipherASN1)
+		if err != nil {
+			if recipientCert != nil || errors.Is(err, ErrPrivateKeyRequired) {
+				return nil, err
+			}
+			continue
+		}
+		key = decrypted
+		break
+	}
+	if key == nil {
+		return nil, ErrPKCS7NotRecipient
+	}
+	sm4e, err := NewSM4(key, iv)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPKCS7, err)
+	}
+	// SM4.Decrypt 不修改传入的密文,解析结果可直接传入
+	return sm4e.Decrypt(eci.EncryptedContent)
+}
+
+func pkcs7IssuerAndSerialOf(cert *x509.Certificate) pkcs7IssuerAndSerial {
+	return pkcs7IssuerAndSerial{
+		Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+		SerialNumber: cert.SerialNumber,
+	}
+}
+
+func pkcs7IssuerAndSerialMatches(ias *pkcs7IssuerAndSerial, cert *x509.Certificate) bool {
+	return ias.SerialNumber != nil && ias.SerialNumber.Cmp(cert.SerialNumber) == 0 &&
+		bytes.Equal(ias.Issuer.FullBytes, cert.RawIssuer)
+}
+
+func marshalPKCS7Certificates(signerCert *x509.Certificate, opts *PKCS7Options) asn1.RawValue {
+	var raw []byte
+	if !opts.OmitSignerCertificate {
+		raw = append(raw, signerCert.Raw...)
+	}
+	for _, cert := range opts.Certificates {
+		raw = append(raw, cert.Raw...)
+	}
+	if len(raw) == 0 {
+		return asn1.RawValue{}
+	}
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw}
+}
+
+func parsePKCS7Certificates(raw asn1.RawValue) ([]*x509.Certificate, error) {
+	if len(raw.Bytes) == 0 {
+		return nil, nil
+	}
+	certificates, err := x509.ParseCertificates(raw.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPKCS7, err)
+	}
+	return certificates, nil
+}
+
+func explicitOctetString(content []byte) (asn1.RawValue, error) {
+	inner, err := asn1.Marshal(content)
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: inner}, nil
+}
+
+func marshalPKCS7ContentInfo(contentType asn1.ObjectIdentifier, content any) ([]byte, error) {
+	inner, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs7ContentInfo{
+		ContentType: contentType,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: inner},
+	})
+}
+
+// unmarshalPKCS7ContentInfo 解析外层 ContentInfo 并按内容类型解析内层结构
+func unmarshalPKCS7ContentInfo(der []byte, content any, contentTypes ...asn1.ObjectIdentifier) error {
+	var info pkcs7ContentInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPKCS7, err)
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("%w: trailing data", ErrInvalidPKCS7)
+	}
+	matched := false
+	for _, contentType := range contentTypes {
+		if info.ContentType.Equal(contentType) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return fmt.Errorf("%w: unexpected content type %v", ErrInvalidPKCS7, info.ContentType)
+	}
+	if _, err := asn1.Unmarshal(info.Content.Bytes, content); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPKCS7, err)
+	}
+	return nil
+}
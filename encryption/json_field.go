@@ -0,0 +1,421 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JSONFieldPrefix 字段密文标记,加密后的字段值为 "sm4:" + Encrypt2Base64(字段原始JSON)
+const JSONFieldPrefix = "sm4:"
+
+var (
+	// ErrInvalidSelector 字段选择器语法错误
+	ErrInvalidSelector = errors.New("json field: invalid selector")
+	// ErrNotJSONArray 流式加解密的输入不是JSON数组
+	ErrNotJSONArray = errors.New("json field: input is not a JSON array")
+)
+
+// JSONFieldEncryptor 字段级JSON加密,只加密选择器匹配的字段,其余内容保持原样可读
+//
+// 选择器为JSONPath子集,以 $ 表示文档根(流式数组中为每个元素):
+//
+//	$.user.idCard          对象成员
+//	$['user']['id-card']   带特殊字符的成员名
+//	$.contacts[*].phone    数组全部元素,.* 匹配对象全部成员
+//	$.contacts[0].phone    数组下标
+//	$..phone               任意深度的成员
+//
+// 匹配的值(字符串、数字、对象、数组均可)整体加密为带 JSONFieldPrefix 标记的字符串,
+// 解密后恢复原始类型;null 保持不变
+//
+// Encrypt 的输入须为明文:已带标记的值(包括从其他字段或记录复制来的密文)同样被加密,
+// 重复加密会再套一层,每次 Decrypt 解开一层。解密时标记后的内容不是合法Base64
+// 或长度不符合密文格式则视为未加密的明文原样保留;未经 Encrypt 处理、恰好形如密文的明文
+// 在解密时会报错,因此带标记的存量明文应先 Encrypt 再存储
+type JSONFieldEncryptor struct {
+	enc       *SM4
+	selectors []*jsonSelector
+}
+
+// NewJSONFieldEncryptor 创建字段级JSON加密
+// 建议使用 WithRandomIV 创建SM4实例,否则相同字段值得到相同密文
+// enc SM4实例
+// selectors 字段选择器,至少一个
+func NewJSONFieldEncryptor(enc *SM4, selectors ...string) (*JSONFieldEncryptor, error) {
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("%w: no selectors", ErrInvalidSelector)
+	}
+	fe := &JSONFieldEncryptor{enc: enc}
+	for _, s := range selectors {
+		sel, err := parseSelector(s)
+		if err != nil {
+			return nil, err
+		}
+		fe.selectors = append(fe.selectors, sel)
+	}
+	return fe, nil
+}
+
+// Encrypt 加密JSON文档中匹配的字段
+// doc JSON文档
+func (fe *JSONFieldEncryptor) Encrypt(doc []byte) ([]byte, error) {
+	return transformDocument(doc, fe.selectors, fe.encryptValue)
+}
+
+// Decrypt 解密JSON文档中匹配且带 JSONFieldPrefix 标记的字段,未加密的字段保持不变
+// doc JSON文档
+func (fe *JSONFieldEncryptor) Decrypt(doc []byte) ([]byte, error) {
+	return transformDocument(doc, fe.decryptSelectors(), fe.decryptValue)
+}
+
+// EncryptArray 流式加密JSON数组,逐个元素读取、加密并写出,内存占用与数组长度无关
+// 选择器作用于每个元素
+// w 输出
+// r 输入,须为一个JSON数组
+func (fe *JSONFieldEncryptor) EncryptArray(w io.Writer, r io.Reader) error {
+	return transformArray(w, r, fe.selectors, fe.encryptValue)
+}
+
+// DecryptArray 流式解密 EncryptArray 的输出
+// w 输出
+// r 输入,须为一个JSON数组
+func (fe *JSONFieldEncryptor) DecryptArray(w io.Writer, r io.Reader) error {
+	return transformArray(w, r, fe.decryptSelectors(), fe.decryptValue)
+}
+
+func transformDocument(doc []byte, selectors []*jsonSelector, fn jsonValueFunc) ([]byte, error) {
+	var value json.RawMessage
+	if err := json.Unmarshal(doc, &value); err != nil {
+		return nil, err
+	}
+	return transformJSON(value, selectors, fn)
+}
+
+func transformArray(w io.Writer, r io.Reader, selectors []*jsonSelector, fn jsonValueFunc) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('[') {
+		return ErrNotJSONArray
+	}
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i := 0; dec.More(); i++ {
+		var item json.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		out, err := transformJSON(item, selectors, fn)
+		if err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+		if i > 0 {
+			out = append([]byte{','}, out...)
+		}
+		if _, err := w.Write(out); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("%w: trailing data after array", ErrNotJSONArray)
+	}
+	_, err = io.WriteString(w, "]")
+	return err
+}
+
+// decryptSelectors 解密时按与加密相反的顺序应用选择器,
+// 使相互嵌套的选择器(如 $.user.phone 与 $.user)先解开外层再解开内层
+func (fe *JSONFieldEncryptor) decryptSelectors() []*jsonSelector {
+	selectors := slices.Clone(fe.selectors)
+	slices.Reverse(selectors)
+	return selectors
+}
+
+// transformJSON 依次应用选择器
+func transformJSON(value json.RawMessage, selectors []*jsonSelector, fn jsonValueFunc) ([]byte, error) {
+	for _, sel := range selectors {
+		var err error
+		if value, _, err = walkJSON(value, sel.steps, fn); err != nil {
+			return nil, fmt.Errorf("json field %s: %w", sel.raw, err)
+		}
+	}
+	return value, nil
+}
+
+// encryptValue 将字段值加密为带标记的字符串
+func (fe *JSONFieldEncryptor) encryptValue(value json.RawMessage) (json.RawMessage, bool, error) {
+	if string(value) == "null" {
+		return value, false, nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, value); err != nil {
+		return nil, false, err
+	}
+	ciphertext, err := fe.enc.Encrypt2Base64(compact.String())
+	if err != nil {
+		return nil, false, err
+	}
+	return marshalJSONString(JSONFieldPrefix + ciphertext), true, nil
+}
+
+// decryptValue 解密带标记的字符串,恢复字段原始JSON
+func (fe *JSONFieldEncryptor) decryptValue(value json.RawMessage) (json.RawMessage, bool, error) {
+	plaintext, ok, err := fe.openJSONField(value)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		return value, false, nil
+	}
+	return plaintext, true, nil
+}
+
+// openJSONField 解密带标记的字段值
+// 未带标记、或标记后的内容不符合密文格式时ok为false,视为明文;
+// 符合密文格式但解密失败或结果不是合法JSON时返回错误,通常意味着密钥错误或密文被篡改
+func (fe *JSONFieldEncryptor) openJSONField(value json.RawMessage) (plaintext []byte, ok bool, err error) {
+	encoded, tagged := taggedJSONField(value)
+	if !tagged {
+		return nil, false, nil
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false, nil
+	}
+	plaintext, err = fe.enc.Decrypt(ciphertext)
+	if errors.Is(err, ErrSM4InvalidCiphertext) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if !json.Valid(plaintext) {
+		return nil, false, fmt.Errorf("%w: decrypted field is not valid JSON", ErrSM4InvalidCiphertext)
+	}
+	return plaintext, true, nil
+}
+
+// taggedJSONField 字段值为带 JSONFieldPrefix 标记的字符串时返回去掉标记的密文
+func taggedJSONField(value json.RawMessage) (string, bool) {
+	if len(value) == 0 || value[0] != '"' {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", false
+	}
+	return strings.CutPrefix(s, JSONFieldPrefix)
+}
+
+// marshalJSONString 编码JSON字符串,不转义HTML字符
+func marshalJSONString(s string) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})
+}
+
+// selectorStepKind 选择器步骤类型
+type selectorStepKind int
+
+const (
+	stepChild    selectorStepKind = iota // .name 或 ['name']
+	stepIndex                            // [n]
+	stepWildcard                         // .* 或 [*]
+	stepDescend                          // .. ,后接一个子步骤
+)
+
+type selectorStep struct {
+	kind  selectorStepKind
+	name  string
+	index int
+}
+
+// matches 对象成员的index为-1,数组元素的key为空
+func (s selectorStep) matches(key string, index int) bool {
+	switch s.kind {
+	case stepChild:
+		return index < 0 && key == s.name
+	case stepIndex:
+		return index == s.index
+	default:
+		return true
+	}
+}
+
+type jsonSelector struct {
+	raw   string
+	steps []selectorStep
+}
+
+// parseSelector 解析字段选择器
+func parseSelector(s string) (*jsonSelector, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("%w: %q must start with $", ErrInvalidSelector, s)
+	}
+	sel := &jsonSelector{raw: s}
+	for i := 1; i < len(s); {
+		var step selectorStep
+		var ok bool
+		switch {
+		case strings.HasPrefix(s[i:], ".."):
+			sel.steps = append(sel.steps, selectorStep{kind: stepDescend})
+			i += 2
+			if i < len(s) && s[i] == '[' {
+				continue
+			}
+			step, i, ok = parseSelectorName(s, i)
+		case s[i] == '.':
+			step, i, ok = parseSelectorName(s, i+1)
+		case s[i] == '[':
+			step, i, ok = parseSelectorBracket(s, i+1)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSelector, s)
+		}
+		sel.steps = append(sel.steps, step)
+	}
+	return sel, nil
+}
+
+// parseSelectorName 解析 . 之后的成员名或 *
+func parseSelectorName(s string, i int) (selectorStep, int, bool) {
+	j := i
+	for j < len(s) && s[j] != '.' && s[j] != '[' {
+		j++
+	}
+	switch name := s[i:j]; name {
+	case "":
+		return selectorStep{}, j, false
+	case "*":
+		return selectorStep{kind: stepWildcard}, j, true
+	default:
+		return selectorStep{kind: stepChild, name: name}, j, true
+	}
+}
+
+// parseSelectorBracket 解析 [ 之后的 *]、n] 或 'name']
+func parseSelectorBracket(s string, i int) (selectorStep, int, bool) {
+	end := strings.IndexByte(s[i:], ']')
+	if end < 0 {
+		return selectorStep{}, i, false
+	}
+	if q := s[i]; q == '\'' || q == '"' {
+		// 成员名中可以包含 ] . 等字符,不能包含引号本身
+		closing := strings.IndexByte(s[i+1:], q)
+		if closing < 0 || !strings.HasPrefix(s[i+1+closing+1:], "]") {
+			return selectorStep{}, i, false
+		}
+		return selectorStep{kind: stepChild, name: s[i+1 : i+1+closing]}, i + 1 + closing + 2, true
+	}
+	inner, next := s[i:i+end], i+end+1
+	if inner == "*" {
+		return selectorStep{kind: stepWildcard}, next, true
+	}
+	n, err := strconv.Atoi(inner)
+	if err != nil || n < 0 || inner[0] == '+' {
+		return selectorStep{}, i, false
+	}
+	return selectorStep{kind: stepIndex, index: n}, next, true
+}
+
+// jsonValueFunc 处理选中的值,返回新值及是否改变
+type jsonValueFunc func(value json.RawMessage) (json.RawMessage, bool, error)
+
+// walkJSON 对value中与steps匹配的值调用fn
+// 只重新编码匹配路径上的对象和数组,其余部分保持原始字节;未改变时返回value本身
+func walkJSON(value json.RawMessage, steps []selectorStep, fn jsonValueFunc) (json.RawMessage, bool, error) {
+	if len(steps) == 0 {
+		return fn(value)
+	}
+	step := steps[0]
+	if step.kind == stepDescend {
+		// 先在当前节点匹配剩余步骤,再在每个子节点上继续 ..
+		value, changed, err := walkJSON(value, steps[1:], fn)
+		if err != nil {
+			return nil, false, err
+		}
+		value, childChanged, err := mapJSONChildren(value, func(_ string, _ int, child json.RawMessage) (json.RawMessage, bool, error) {
+			return walkJSON(child, steps, fn)
+		})
+		return value, changed || childChanged, err
+	}
+	return mapJSONChildren(value, func(key string, index int, child json.RawMessage) (json.RawMessage, bool, error) {
+		if !step.matches(key, index) {
+			return child, false, nil
+		}
+		return walkJSON(child, steps[1:], fn)
+	})
+}
+
+type jsonMember struct {
+	key   string
+	value json.RawMessage
+}
+
+// mapJSONChildren 对对象成员(index为-1)或数组元素(key为空)调用fn,标量原样返回
+func mapJSONChildren(value json.RawMessage, fn func(key string, index int, child json.RawMessage) (json.RawMessage, bool, error)) (json.RawMessage, bool, error) {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 || (value[0] != '{' && value[0] != '[') {
+		return value, false, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(value))
+	if _, err := dec.Token(); err != nil {
+		return nil, false, err
+	}
+	var members []jsonMember
+	changed := false
+	for i := 0; dec.More(); i++ {
+		key, index := "", i
+		if value[0] == '{' {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, false, err
+			}
+			key, index = tok.(string), -1
+		}
+		var child json.RawMessage
+		if err := dec.Decode(&child); err != nil {
+			return nil, false, err
+		}
+		child, childChanged, err := fn(key, index, child)
+		if err != nil {
+			return nil, false, err
+		}
+		changed = changed || childChanged
+		members = append(members, jsonMember{key: key, value: child})
+	}
+	if !changed {
+		return value, false, nil
+	}
+
+	out := []byte{value[0]}
+	for i, m := range members {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		if value[0] == '{' {
+			out = append(out, marshalJSONString(m.key)...)
+			out = append(out, ':')
+		}
+		out = append(out, m.value...)
+	}
+	if value[0] == '{' {
+		return append(out, '}'), true, nil
+	}
+	return append(out, ']'), true, nil
+}
@@ -0,0 +1,109 @@
+package encryption
+
+import (
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// DefaultPBKDF2Iterations NewSM4FromPassphrase 默认的PBKDF2迭代次数
+const DefaultPBKDF2Iterations = 100000
+
+const minPassphraseSaltSize = 8
+
+var (
+	// ErrInvalidKeyLength 派生密钥长度非法
+	ErrInvalidKeyLength = errors.New("kdf: invalid key length")
+	// ErrInvalidSalt 盐值过短
+	ErrInvalidSalt = errors.New("kdf: invalid salt")
+)
+
+// SM3KDF GM/T 0003 密钥派生函数: K = SM3(Z||ct1) || SM3(Z||ct2) || ...,计数器从1开始
+// keyLen 派生密钥字节长度
+// z 共享秘密,多段按顺序拼接
+func SM3KDF(keyLen int, z ...[]byte) ([]byte, error) {
+	if keyLen <= 0 || uint64(keyLen) > uint64(^uint32(0))*SM3Size {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidKeyLength, keyLen)
+	}
+	key := make([]byte, 0, keyLen+SM3Size)
+	var ct [4]byte
+	h := NewSM3()
+	for counter := uint32(1); len(key) < keyLen; counter++ {
+		h.Reset()
+		for _, b := range z {
+			h.Write(b)
+		}
+		binary.BigEndian.PutUint32(ct[:], counter)
+		h.Write(ct[:])
+		key = h.Sum(key)
+	}
+	return key[:keyLen], nil
+}
+
+// PBKDF2SM3 以HMAC-SM3为伪随机函数的 PBKDF2(RFC 8018)
+// password 口令
+// salt 盐值,建议至少16字节随机数
+// iterations 迭代次数
+// keyLen 派生密钥字节长度
+func PBKDF2SM3(password, salt []byte, iterations, keyLen int) ([]byte, error) {
+	if iterations <= 0 {
+		return nil, fmt.Errorf("kdf: iterations must be positive, got %d", iterations)
+	}
+	key, err := pbkdf2.Key(NewSM3, string(password), salt, iterations, keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeyLength, err)
+	}
+	return key, nil
+}
+
+// HKDFSM3 以HMAC-SM3为基础的 HKDF(RFC 5869),先提取再扩展
+// secret 输入密钥材料
+// salt 盐值,可为nil
+// info 上下文信息,可为nil
+// keyLen 派生密钥字节长度,不超过 255*32
+func HKDFSM3(secret, salt, info []byte, keyLen int) ([]byte, error) {
+	key, err := hkdf.Key(NewSM3, secret, salt, string(info), keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeyLength, err)
+	}
+	return key, nil
+}
+
+// HKDFSM3Extract HKDF 提取步骤,返回32字节伪随机密钥 PRK
+func HKDFSM3Extract(secret, salt []byte) ([]byte, error) {
+	return hkdf.Extract(NewSM3, secret, salt)
+}
+
+// HKDFSM3Expand HKDF 扩展步骤
+// prk HKDFSM3Extract 得到的伪随机密钥
+// info 上下文信息,可为nil
+// keyLen 派生密钥字节长度,不超过 255*32
+func HKDFSM3Expand(prk, info []byte, keyLen int) ([]byte, error) {
+	key, err := hkdf.Expand(NewSM3, prk, string(info), keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeyLength, err)
+	}
+	return key, nil
+}
+
+// NewSM4FromPassphrase 使用 PBKDF2-SM3 由口令和盐值派生SM4密钥和IV(共32字节)
+// 相同的口令、盐值和迭代次数总是得到相同的密钥和IV
+// passphrase 口令
+// salt 盐值,至少8字节,需与密文一同保存
+// iterations 迭代次数,<=0 时使用 DefaultPBKDF2Iterations
+// opts SM4构造选项
+func NewSM4FromPassphrase(passphrase, salt []byte, iterations int, opts ...SM4Option) (*SM4, error) {
+	if len(salt) < minPassphraseSaltSize {
+		return nil, fmt.Errorf("%w: salt must be at least %d bytes", ErrInvalidSalt, minPassphraseSaltSize)
+	}
+	if iterations <= 0 {
+		iterations = DefaultPBKDF2Iterations
+	}
+	derived, err := PBKDF2SM3(passphrase, salt, iterations, 2*sm4BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	return NewSM4(derived[:sm4BlockSize], derived[sm4BlockSize:], opts...)
+}
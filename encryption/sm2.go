@@ -5,14 +5,46 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/tjfoc/gmsm/sm2"
 )
 
+var (
+	// ErrInvalidPublicKey 公钥格式错误或不在SM2曲线上
+	ErrInvalidPublicKey = errors.New("sm2: invalid public key")
+	// ErrUnsupportedPublicKey 公钥编码格式不受支持
+	ErrUnsupportedPublicKey = errors.New("sm2: unsupported public key encoding")
+	// ErrInvalidPrivateKey 私钥格式错误或D超出范围
+	ErrInvalidPrivateKey = errors.New("sm2: invalid private key")
+	// ErrKeyMismatch 私钥与公钥不匹配
+	ErrKeyMismatch = errors.New("sm2: private key does not match public key")
+	// ErrPublicKeyRequired 当前SM2实例未持有公钥
+	ErrPublicKeyRequired = errors.New("sm2: public key required")
+	// ErrPrivateKeyRequired 当前SM2实例未持有私钥(如仅用于加密的实例)
+	ErrPrivateKeyRequired = errors.New("sm2: private key required")
+	// ErrEmptyPlaintext SM2加密的明文不能为空(GM/T 0003 要求KDF输出长度大于0)
+	ErrEmptyPlaintext = errors.New("sm2: empty plaintext")
+)
+
+// PublicKeyFormat 公钥编码格式
+type PublicKeyFormat int
+
+const (
+	// PublicKeyUncompressed 非压缩格式 04||X||Y,共65字节
+	PublicKeyUncompressed PublicKeyFormat = iota
+	// PublicKeyCompressed 压缩格式 02/03||X,共33字节
+	PublicKeyCompressed
+	// PublicKeyRaw 不带前缀的裸格式 X||Y,共64字节
+	PublicKeyRaw
+)
+
 type SM2 struct {
 	publicKey  *sm2.PublicKey
 	privateKey *sm2.PrivateKey
+	userID     []byte
 }
 
 // NewSM2
@@ -31,38 +63,211 @@ func NewSM2(publicKeyHex, privateKeyHex string) (sm2e *SM2, err error) {
 	return
 }
 
+// NewSM2Encryptor 新建仅持有公钥的SM2,只能用于加密和验签
+// publicKeyHex 公钥16进制字符串
+func NewSM2Encryptor(publicKeyHex string) (*SM2, error) {
+	publicKey, err := DecodePublicKey(publicKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	return &SM2{publicKey: publicKey}, nil
+}
+
+// NewSM2Decryptor 新建仅由私钥构造的SM2,公钥由D推导,可用于加解密及签名验签
+// privateKeyHex 私钥16进制字符串
+func NewSM2Decryptor(privateKeyHex string) (*SM2, error) {
+	privateKeyBytes, err := hex.DecodeString(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	privateKey, err := derivePrivateKey(new(big.Int).SetBytes(privateKeyBytes))
+	if err != nil {
+		return nil, err
+	}
+	return &SM2{publicKey: &privateKey.PublicKey, privateKey: privateKey}, nil
+}
+
+// HasPrivateKey 当前实例是否持有私钥
+func (enc *SM2) HasPrivateKey() bool {
+	return enc.privateKey != nil
+}
+
+// GenerateSM2KeyPair 生成新的SM2密钥对
+// 生成的公私钥可通过 PublicKeyHex/PrivateKeyHex 等方法导出(与java中org.bouncycastle.crypto生成的公私钥完全互通使用)
+func GenerateSM2KeyPair() (*SM2, error) {
+	privateKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &SM2{publicKey: &privateKey.PublicKey, privateKey: privateKey}, nil
+}
+
+// PublicKeyHex 导出公钥16进制字符串,格式为 04||X||Y
+func (enc *SM2) PublicKeyHex() string {
+	return hex.EncodeToString(EncodePublicKey(enc.publicKey))
+}
+
+// PublicKeyBase64 导出公钥Base64字符串,格式为 04||X||Y
+func (enc *SM2) PublicKeyBase64() string {
+	return base64.StdEncoding.EncodeToString(EncodePublicKey(enc.publicKey))
+}
+
+// PrivateKeyHex 导出私钥16进制字符串,D补齐为32字节,未持有私钥时返回空字符串
+func (enc *SM2) PrivateKeyHex() string {
+	if enc.privateKey == nil {
+		return ""
+	}
+	return hex.EncodeToString(EncodePrivateKey(enc.privateKey))
+}
+
+// PrivateKeyBase64 导出私钥Base64字符串,D补齐为32字节,未持有私钥时返回空字符串
+func (enc *SM2) PrivateKeyBase64() string {
+	if enc.privateKey == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(EncodePrivateKey(enc.privateKey))
+}
+
+// PublicKeyHexWithFormat 按指定编码格式导出公钥16进制字符串
+func (enc *SM2) PublicKeyHexWithFormat(format PublicKeyFormat) (string, error) {
+	encoded, err := EncodePublicKeyWithFormat(enc.publicKey, format)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(encoded), nil
+}
+
+// PublicKeyBase64WithFormat 按指定编码格式导出公钥Base64字符串
+func (enc *SM2) PublicKeyBase64WithFormat(format PublicKeyFormat) (string, error) {
+	encoded, err := EncodePublicKeyWithFormat(enc.publicKey, format)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encoded), nil
+}
+
+// EncodePublicKey 将 sm2.PublicKey 对象序列化为非压缩格式 04||X||Y,X、Y均补齐为曲线字节长度
+// 与 DecodePublicKey 互逆
+func EncodePublicKey(publicKey *sm2.PublicKey) []byte {
+	byteLen := sm2ByteLen()
+	encoded := make([]byte, 1+2*byteLen)
+	encoded[0] = 0x04
+	publicKey.X.FillBytes(encoded[1 : byteLen+1])
+	publicKey.Y.FillBytes(encoded[byteLen+1:])
+	return encoded
+}
+
+// EncodePublicKeyWithFormat 按指定编码格式序列化 sm2.PublicKey 对象
+func EncodePublicKeyWithFormat(publicKey *sm2.PublicKey, format PublicKeyFormat) ([]byte, error) {
+	switch format {
+	case PublicKeyUncompressed:
+		return EncodePublicKey(publicKey), nil
+	case PublicKeyCompressed:
+		byteLen := sm2ByteLen()
+		encoded := make([]byte, 1+byteLen)
+		encoded[0] = 0x02 | byte(publicKey.Y.Bit(0))
+		publicKey.X.FillBytes(encoded[1:])
+		return encoded, nil
+	case PublicKeyRaw:
+		return EncodePublicKey(publicKey)[1:], nil
+	default:
+		return nil, fmt.Errorf("%w: unknown format %d", ErrUnsupportedPublicKey, format)
+	}
+}
+
+// EncodePrivateKey 将 sm2.PrivateKey 对象序列化为定长D,高位补零至曲线字节长度
+// 与 DecodePrivateKey 互逆
+func EncodePrivateKey(privateKey *sm2.PrivateKey) []byte {
+	return privateKey.D.FillBytes(make([]byte, sm2ByteLen()))
+}
+
+// sm2ByteLen SM2曲线坐标及私钥的字节长度
+func sm2ByteLen() int {
+	return (sm2.P256Sm2().Params().BitSize + 7) / 8
+}
+
 // DecodePublicKey 公钥字符串还原为 sm2.PublicKey 对象(与java中org.bouncycastle.crypto生成的公私钥完全互通使用)
+// 自动识别非压缩(04||X||Y)、压缩(02/03||X)及64字节裸格式(X||Y)
+// 格式错误、不在曲线上的公钥返回 ErrInvalidPublicKey,混合格式(06/07)返回 ErrUnsupportedPublicKey
 // publicKeyHex: 公钥16进制字符串
 func DecodePublicKey(publicKeyHex string) (*sm2.PublicKey, error) {
 	publicKeyBytes, err := hex.DecodeString(publicKeyHex)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+	return decodePublicKeyBytes(publicKeyBytes)
+}
+
+// decodePublicKeyBytes 按长度及前缀识别公钥编码格式
+func decodePublicKeyBytes(publicKeyBytes []byte) (*sm2.PublicKey, error) {
+	byteLen := sm2ByteLen()
+	var x, y *big.Int
+	switch {
+	case len(publicKeyBytes) == 0:
+		return nil, fmt.Errorf("%w: empty key", ErrInvalidPublicKey)
+	case len(publicKeyBytes) == 2*byteLen:
+		// 提取 x 和 y 坐标字节切片
+		x = new(big.Int).SetBytes(publicKeyBytes[:byteLen])
+		y = new(big.Int).SetBytes(publicKeyBytes[byteLen:])
+	case publicKeyBytes[0] == 0x04 && len(publicKeyBytes) == 1+2*byteLen:
+		x = new(big.Int).SetBytes(publicKeyBytes[1 : byteLen+1])
+		y = new(big.Int).SetBytes(publicKeyBytes[byteLen+1:])
+	case (publicKeyBytes[0] == 0x02 || publicKeyBytes[0] == 0x03) && len(publicKeyBytes) == 1+byteLen:
+		var err error
+		x = new(big.Int).SetBytes(publicKeyBytes[1:])
+		if y, err = decompressY(x, publicKeyBytes[0] == 0x03); err != nil {
+			return nil, err
+		}
+	case publicKeyBytes[0] == 0x06 || publicKeyBytes[0] == 0x07:
+		return nil, fmt.Errorf("%w: hybrid point", ErrUnsupportedPublicKey)
+	default:
+		return nil, fmt.Errorf("%w: unrecognized encoding of %d bytes with prefix 0x%02x",
+			ErrInvalidPublicKey, len(publicKeyBytes), publicKeyBytes[0])
 	}
-	// 提取 x 和 y 坐标字节切片
-	curve := sm2.P256Sm2().Params()
-	byteLen := (curve.BitSize + 7) / 8
-	xBytes := publicKeyBytes[1 : byteLen+1]
-	yBytes := publicKeyBytes[byteLen+1 : 2*byteLen+1]
-	// 将字节切片转换为大整数
-	x := new(big.Int).SetBytes(xBytes)
-	y := new(big.Int).SetBytes(yBytes)
 	// 创建 sm2.PublicKey 对象
 	publicKey := &sm2.PublicKey{
-		Curve: curve,
+		Curve: sm2.P256Sm2(),
 		X:     x,
 		Y:     y,
 	}
+	if err := validatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
 	return publicKey, nil
 }
 
+// decompressY 由X坐标及Y奇偶性恢复Y坐标: y^2 = x^3 + ax + b (mod p)
+func decompressY(x *big.Int, odd bool) (*big.Int, error) {
+	params := sm2.P256Sm2().Params()
+	p := params.P
+	if x.Cmp(p) >= 0 {
+		return nil, fmt.Errorf("%w: coordinate out of range", ErrInvalidPublicKey)
+	}
+	// SM2推荐曲线 a = p - 3
+	y2 := new(big.Int).Exp(x, big.NewInt(3), p)
+	threeX := new(big.Int).Mul(x, big.NewInt(3))
+	y2.Sub(y2, threeX)
+	y2.Add(y2, params.B)
+	y2.Mod(y2, p)
+	y := new(big.Int).ModSqrt(y2, p)
+	if y == nil {
+		return nil, fmt.Errorf("%w: point is not on curve", ErrInvalidPublicKey)
+	}
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(p, y)
+	}
+	return y, nil
+}
+
 // DecodePrivateKey 将私钥字符串反序列化转为私钥对象
 // 私钥还原为 sm2.PrivateKey对象(与java中org.bouncycastle.crypto生成的公私钥完全互通使用)
+// D不在[1, n-2]范围内返回 ErrInvalidPrivateKey,与公钥不匹配返回 ErrKeyMismatch
 // privateKeyHex 私钥16进制字符串
 // publicKeyHex 公钥16进制字符串
 func DecodePrivateKey(privateKeyHex, publicKeyHex string) (*sm2.PrivateKey, error) {
 	privateKeyBytes, err := hex.DecodeString(privateKeyHex)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
 	}
 	publicKey, err := DecodePublicKey(publicKeyHex)
 	if err != nil {
@@ -75,50 +280,122 @@ func DecodePrivateKey(privateKeyHex, publicKeyHex string) (*sm2.PrivateKey, erro
 		PublicKey: *publicKey,
 		D:         d,
 	}
+	if err := validatePrivateKey(privateKey); err != nil {
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+// derivePrivateKey 由D推导公钥构造私钥对象,D须在[1, n-2]范围内
+func derivePrivateKey(d *big.Int) (*sm2.PrivateKey, error) {
+	curve := sm2.P256Sm2()
+	maxD := new(big.Int).Sub(curve.Params().N, big.NewInt(2))
+	if d.Sign() <= 0 || d.Cmp(maxD) > 0 {
+		return nil, fmt.Errorf("%w: D out of range", ErrInvalidPrivateKey)
+	}
+	privateKey := &sm2.PrivateKey{D: d}
+	privateKey.Curve = curve
+	privateKey.X, privateKey.Y = curve.ScalarBaseMult(d.FillBytes(make([]byte, sm2ByteLen())))
 	return privateKey, nil
 }
 
+// validatePublicKey 校验公钥坐标在有限域内且点在SM2曲线上
+func validatePublicKey(publicKey *sm2.PublicKey) error {
+	if publicKey == nil || publicKey.X == nil || publicKey.Y == nil {
+		return fmt.Errorf("%w: missing coordinates", ErrInvalidPublicKey)
+	}
+	curve := sm2.P256Sm2()
+	p := curve.Params().P
+	if publicKey.X.Sign() < 0 || publicKey.X.Cmp(p) >= 0 || publicKey.Y.Sign() < 0 || publicKey.Y.Cmp(p) >= 0 {
+		return fmt.Errorf("%w: coordinate out of range", ErrInvalidPublicKey)
+	}
+	if !curve.IsOnCurve(publicKey.X, publicKey.Y) {
+		return fmt.Errorf("%w: point is not on curve", ErrInvalidPublicKey)
+	}
+	return nil
+}
+
+// validatePrivateKey 校验私钥D在[1, n-2]范围内且与公钥匹配
+func validatePrivateKey(privateKey *sm2.PrivateKey) error {
+	if privateKey == nil || privateKey.D == nil {
+		return fmt.Errorf("%w: missing D", ErrInvalidPrivateKey)
+	}
+	derived, err := derivePrivateKey(privateKey.D)
+	if err != nil {
+		return err
+	}
+	if derived.X.Cmp(privateKey.X) != 0 || derived.Y.Cmp(privateKey.Y) != 0 {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
 // Decrypt 使用私钥对象解密密文字符串
 // ciphertext 待解密密文字符串
-// mode 加密模式:0=C1C3C2,1=C1C2C3
-func (enc *SM2) Decrypt(ciphertext []byte, mode int) ([]byte, error) {
-	return sm2.Decrypt(enc.privateKey, ciphertext, mode)
+// format 密文格式,见 CipherFormat
+func (enc *SM2) Decrypt(ciphertext []byte, format CipherFormat) ([]byte, error) {
+	if enc.privateKey == nil {
+		return nil, ErrPrivateKeyRequired
+	}
+	c1c3c2, err := ConvertCiphertext(ciphertext, format, CipherC1C3C2)
+	if err != nil {
+		return nil, err
+	}
+	// C3校验失败时 gmsm 仍返回解密结果,未经认证的明文不能交给调用方
+	plaintext, err := sm2.Decrypt(enc.privateKey, c1c3c2, sm2.C1C3C2)
+	if err != nil {
+		return nil, err
+	}
+	return plaintext, nil
+}
+
+// DecryptEncoded 使用私钥对象解密指定编码的密文字符串
+// ciphertext 待解密密文字符串
+// format 密文格式,见 CipherFormat
+// encoding 密文编码,见 Encoding
+func (enc *SM2) DecryptEncoded(ciphertext string, format CipherFormat, encoding Encoding) ([]byte, error) {
+	decodeByes, err := encoding.Decode(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return enc.Decrypt(decodeByes, format)
 }
 
 // DecryptHex 使用私钥对象解密密Hex文字符串
 // ciphertext 待解密密文字符串
-// mode 加密模式:0=C1C3C2,1=C1C2C3
+// format 密文格式,见 CipherFormat
 // obj 解码对象
-func (enc *SM2) DecryptHex(ciphertext string, mode int) ([]byte, error) {
+func (enc *SM2) DecryptHex(ciphertext string, format CipherFormat) ([]byte, error) {
 	decodeByes, err := hex.DecodeString(ciphertext)
 	if err != nil {
 		return nil, err
 	}
-	return sm2.Decrypt(enc.privateKey, decodeByes, mode)
+	return enc.Decrypt(decodeByes, format)
 }
 
 // DecryptBase64 使用私钥对象解密密Base64文字符串
 // ciphertext 待解密密文字符串
-// mode 加密模式:0=C1C3C2,1=C1C2C3
+// format 密文格式,见 CipherFormat
 // obj 解码对象
-func (enc *SM2) DecryptBase64(ciphertext string, mode int) ([]byte, error) {
+func (enc *SM2) DecryptBase64(ciphertext string, format CipherFormat) ([]byte, error) {
 	decodeByes, err := base64.StdEncoding.DecodeString(ciphertext)
 	if err != nil {
 		return nil, err
 	}
-	return sm2.Decrypt(enc.privateKey, decodeByes, mode)
+	return enc.Decrypt(decodeByes, format)
 }
 
 // DecryptObject 使用私钥对象解密密文字符串
+// 密文须为16进制,JSON解码;其他格式使用 DecryptObjectEncoded
 // ciphertext 待解密密文字符串
-// mode 加密模式:0=C1C3C2,1=C1C2C3
+// format 密文格式,见 CipherFormat
 // obj 解码对象
-func (enc *SM2) DecryptObject(ciphertext string, mode int, obj any) error {
+func (enc *SM2) DecryptObject(ciphertext string, format CipherFormat, obj any) error {
 	decodeString, err := hex.DecodeString(ciphertext)
 	if err != nil {
 		return err
 	}
-	decrypt, err := sm2.Decrypt(enc.privateKey, decodeString, mode)
+	decrypt, err := enc.Decrypt(decodeString, format)
 	if err != nil {
 		return err
 	}
@@ -127,16 +404,45 @@ func (enc *SM2) DecryptObject(ciphertext string, mode int, obj any) error {
 
 // Encrypt 加密
 // plaintext 待加密明文字符串
-// mode 加密模式:0=C1C3C2,1=C1C2C3
-func (enc *SM2) Encrypt(plaintext string, mode int) ([]byte, error) {
-	return sm2.Encrypt(enc.publicKey, []byte(plaintext), rand.Reader, mode)
+// format 密文格式,见 CipherFormat
+func (enc *SM2) Encrypt(plaintext string, format CipherFormat) ([]byte, error) {
+	return enc.EncryptBytes([]byte(plaintext), format)
+}
+
+// EncryptBytes 加密二进制数据,明文为空时返回 ErrEmptyPlaintext
+// plaintext 待加密明文
+// format 密文格式,见 CipherFormat
+func (enc *SM2) EncryptBytes(plaintext []byte, format CipherFormat) ([]byte, error) {
+	if enc.publicKey == nil {
+		return nil, ErrPublicKeyRequired
+	}
+	if len(plaintext) == 0 {
+		return nil, ErrEmptyPlaintext
+	}
+	ciphertext, err := sm2.Encrypt(enc.publicKey, plaintext, rand.Reader, sm2.C1C3C2)
+	if err != nil {
+		return nil, err
+	}
+	return ConvertCiphertext(ciphertext, CipherC1C3C2, format)
+}
+
+// EncryptEncoded 加密并按指定编码返回密文字符串
+// plaintext 待加密明文
+// format 密文格式,见 CipherFormat
+// encoding 密文编码,见 Encoding
+func (enc *SM2) EncryptEncoded(plaintext []byte, format CipherFormat, encoding Encoding) (string, error) {
+	ciphertext, err := enc.EncryptBytes(plaintext, format)
+	if err != nil {
+		return "", err
+	}
+	return encoding.Encode(ciphertext)
 }
 
 // Encrypt2Hex 加密
 // plaintext 待加密明文字符串
-// mode 加密模式:0=C1C3C2,1=C1C2C3
-func (enc *SM2) Encrypt2Hex(plaintext string, mode int) (string, error) {
-	encryptedByts, err := enc.Encrypt(plaintext, mode)
+// format 密文格式,见 CipherFormat
+func (enc *SM2) Encrypt2Hex(plaintext string, format CipherFormat) (string, error) {
+	encryptedByts, err := enc.Encrypt(plaintext, format)
 	if err != nil {
 		return "", err
 	}
@@ -145,9 +451,9 @@ func (enc *SM2) Encrypt2Hex(plaintext string, mode int) (string, error) {
 
 // Encrypt2Base64 加密
 // plaintext 待加密明文字符串
-// mode 加密模式:0=C1C3C2,1=C1C2C3
-func (enc *SM2) Encrypt2Base64(plaintext string, mode int) (string, error) {
-	encrypt, err := enc.Encrypt(plaintext, mode)
+// format 密文格式,见 CipherFormat
+func (enc *SM2) Encrypt2Base64(plaintext string, format CipherFormat) (string, error) {
+	encrypt, err := enc.Encrypt(plaintext, format)
 	if err != nil {
 		return "", err
 	}
@@ -155,12 +461,40 @@ func (enc *SM2) Encrypt2Base64(plaintext string, mode int) (string, error) {
 }
 
 // EncryptObject 加密JSON对象
+// 返回原始密文字节;需要与 DecryptObject 对称时使用 EncryptObjectEncoded
 // obj 待加密对象
-// mode 加密模式:0=C1C3C2,1=C1C2C3
-func (enc *SM2) EncryptObject(obj any, mode int) ([]byte, error) {
+// format 密文格式,见 CipherFormat
+func (enc *SM2) EncryptObject(obj any, format CipherFormat) ([]byte, error) {
 	marshal, err := json.Marshal(obj)
 	if err != nil {
 		return nil, err
 	}
-	return enc.Encrypt(string(marshal), mode)
+	return enc.EncryptBytes(marshal, format)
+}
+
+// EncryptObjectEncoded 序列化并加密对象,按指定编码返回密文字符串,与 DecryptObjectEncoded 对称
+// obj 待加密对象
+// format 密文格式,见 CipherFormat
+// c 序列化方式,nil 为 JSONCodec
+// encoding 密文编码,见 Encoding
+func (enc *SM2) EncryptObjectEncoded(obj any, format CipherFormat, c Codec, encoding Encoding) (string, error) {
+	marshal, err := codecOrDefault(c).Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return enc.EncryptEncoded(marshal, format, encoding)
+}
+
+// DecryptObjectEncoded 解密 EncryptObjectEncoded 的输出并反序列化到对象
+// ciphertext 待解密密文字符串
+// format 密文格式,见 CipherFormat
+// c 序列化方式,须与加密时一致,nil 为 JSONCodec
+// encoding 密文编码,须与加密时一致
+// obj 解码对象
+func (enc *SM2) DecryptObjectEncoded(ciphertext string, format CipherFormat, c Codec, encoding Encoding, obj any) error {
+	decrypt, err := enc.DecryptEncoded(ciphertext, format, encoding)
+	if err != nil {
+		return err
+	}
+	return codecOrDefault(c).Unmarshal(decrypt, obj)
 }
@@ -0,0 +1,310 @@
+package encryption
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/tjfoc/gmsm/sm2"
+	"github.com/tjfoc/gmsm/sm3"
+	"github.com/tjfoc/gmsm/x509"
+)
+
+const (
+	pemTypeCertificate        = "CERTIFICATE"
+	pemTypeCertificateRequest = "CERTIFICATE REQUEST"
+
+	defaultCertificateValidity = 365 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidCertificate 证书或证书请求解析失败、签名错误或不含SM2公钥
+	ErrInvalidCertificate = errors.New("sm2: invalid certificate")
+	// ErrCertificateVerify 证书链校验失败
+	ErrCertificateVerify = errors.New("sm2: certificate verification failed")
+)
+
+// CertificateOptions 证书及证书请求(CSR)选项,签名算法固定为SM2-with-SM3
+type CertificateOptions struct {
+	Subject        pkix.Name
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []net.IP
+
+	// SerialNumber 证书序列号,为nil时随机生成128位序列号
+	SerialNumber *big.Int
+	// NotBefore 生效时间,为零值时取当前时间
+	NotBefore time.Time
+	// NotAfter 失效时间,为零值时取 NotBefore 之后一年
+	NotAfter time.Time
+
+	// KeyUsage 密钥用法,为0时CA证书取 CertSign|CRLSign,终端证书取 DigitalSignature|KeyEncipherment
+	KeyUsage    x509.KeyUsage
+	ExtKeyUsage []x509.ExtKeyUsage
+	// IsCA IssueCertificate 签发中间CA证书时设为true
+	IsCA bool
+	// MaxPathLen CA证书路径长度约束,0表示不限制
+	MaxPathLen int
+}
+
+// NewSM2FromCertificate 从证书创建仅持有公钥的SM2,用于验签或加密
+func NewSM2FromCertificate(cert *x509.Certificate) (*SM2, error) {
+	publicKey, err := certificatePublicKey(cert)
+	if err != nil {
+		return nil, err
+	}
+	return &SM2{publicKey: publicKey}, nil
+}
+
+// NewSM2FromCertificatePEM 从PEM格式证书创建仅持有公钥的SM2
+func NewSM2FromCertificatePEM(certPEM []byte) (*SM2, error) {
+	certs, err := ParseCertificatesPEM(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	return NewSM2FromCertificate(certs[0])
+}
+
+// CreateCertificateRequest 使用私钥生成DER格式证书请求(PKCS#10)
+// opts 证书请求选项,只使用 Subject 及 DNSNames/EmailAddresses/IPAddresses
+func (enc *SM2) CreateCertificateRequest(opts *CertificateOptions) ([]byte, error) {
+	if enc.privateKey == nil {
+		return nil, ErrPrivateKeyRequired
+	}
+	if opts == nil {
+		opts = &CertificateOptions{}
+	}
+	template := &x509.CertificateRequest{
+		SignatureAlgorithm: x509.SM2WithSM3,
+		Subject:            opts.Subject,
+		DNSNames:           opts.DNSNames,
+		EmailAddresses:     opts.EmailAddresses,
+		IPAddresses:        opts.IPAddresses,
+	}
+	return x509.CreateCertificateRequest(rand.Reader, template, enc.privateKey)
+}
+
+// CreateCACertificate 使用私钥生成自签名CA证书(DER格式)
+// opts 证书选项
+func (enc *SM2) CreateCACertificate(opts *CertificateOptions) ([]byte, error) {
+	if enc.privateKey == nil {
+		return nil, ErrPrivateKeyRequired
+	}
+	if opts == nil {
+		opts = &CertificateOptions{}
+	}
+	template, err := newCertificateTemplate(opts, true)
+	if err != nil {
+		return nil, err
+	}
+	template.Subject = opts.Subject
+	template.DNSNames = opts.DNSNames
+	template.EmailAddresses = opts.EmailAddresses
+	template.IPAddresses = opts.IPAddresses
+	template.SubjectKeyId = subjectKeyID(enc.publicKey)
+	return x509.CreateCertificate(template, template, enc.publicKey, enc.privateKey)
+}
+
+// IssueCertificate 使用CA私钥根据证书请求签发终端证书(DER格式)
+// 证书主题及备用名称取自证书请求,证书请求的签名会先被校验;opts.IsCA 为true时签发中间CA证书
+// csrDER DER格式证书请求
+// caCert CA证书,须与当前私钥对应
+// opts 证书选项,Subject 及备用名称字段被忽略
+func (enc *SM2) IssueCertificate(csrDER []byte, caCert *x509.Certificate, opts *CertificateOptions) ([]byte, error) {
+	if enc.privateKey == nil {
+		return nil, ErrPrivateKeyRequired
+	}
+	csr, err := ParseCertificateRequest(csrDER)
+	if err != nil {
+		return nil, err
+	}
+	caPublicKey, err := certificatePublicKey(caCert)
+	if err != nil {
+		return nil, err
+	}
+	if caPublicKey.X.Cmp(enc.publicKey.X) != 0 || caPublicKey.Y.Cmp(enc.publicKey.Y) != 0 {
+		return nil, fmt.Errorf("%w: CA certificate does not match private key", ErrKeyMismatch)
+	}
+	if !caCert.IsCA {
+		return nil, fmt.Errorf("%w: issuer is not a CA", ErrInvalidCertificate)
+	}
+	subjectPublicKey, err := sm2PublicKeyOf(csr.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &CertificateOptions{}
+	}
+	template, err := newCertificateTemplate(opts, opts.IsCA)
+	if err != nil {
+		return nil, err
+	}
+	template.Subject = csr.Subject
+	template.DNSNames = csr.DNSNames
+	template.EmailAddresses = csr.EmailAddresses
+	template.IPAddresses = csr.IPAddresses
+	template.SubjectKeyId = subjectKeyID(subjectPublicKey)
+	return x509.CreateCertificate(template, caCert, subjectPublicKey, enc.privateKey)
+}
+
+// ParseCertificateRequest 解析DER格式证书请求并校验其签名
+func ParseCertificateRequest(csrDER []byte) (*x509.CertificateRequest, error) {
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	return csr, nil
+}
+
+// ParseCertificate 解析DER格式证书
+func ParseCertificate(certDER []byte) (*x509.Certificate, error) {
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	return cert, nil
+}
+
+// ParseCertificatesPEM 解析PEM格式证书,支持包含多个证书的证书链文件
+func ParseCertificatesPEM(certPEM []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		block, rest := pem.Decode(certPEM)
+		if block == nil {
+			break
+		}
+		certPEM = rest
+		if block.Type != pemTypeCertificate {
+			continue
+		}
+		cert, err := ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: no CERTIFICATE PEM block", ErrInvalidCertificate)
+	}
+	return certs, nil
+}
+
+// EncodeCertificatePEM 将DER格式证书编码为PEM
+func EncodeCertificatePEM(certDER []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypeCertificate, Bytes: certDER})
+}
+
+// EncodeCertificateRequestPEM 将DER格式证书请求编码为PEM
+func EncodeCertificateRequestPEM(csrDER []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypeCertificateRequest, Bytes: csrDER})
+}
+
+// VerifyCertificateChain 校验终端证书能否经中间证书链接到受信任的根证书
+// 返回校验通过的证书链,不限制扩展密钥用法
+// leaf 终端证书
+// intermediates 中间CA证书,可为空
+// roots 受信任的根证书
+func VerifyCertificateChain(leaf *x509.Certificate, intermediates, roots []*x509.Certificate) ([][]*x509.Certificate, error) {
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("%w: at least one root required", ErrCertificateVerify)
+	}
+	opts := x509.VerifyOptions{
+		Intermediates: x509.NewCertPool(),
+		Roots:         x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	for _, cert := range intermediates {
+		opts.Intermediates.AddCert(cert)
+	}
+	for _, cert := range roots {
+		opts.Roots.AddCert(cert)
+	}
+	chains, err := leaf.Verify(opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCertificateVerify, err)
+	}
+	return chains, nil
+}
+
+func newCertificateTemplate(opts *CertificateOptions, isCA bool) (*x509.Certificate, error) {
+	serialNumber := opts.SerialNumber
+	if serialNumber == nil {
+		var err error
+		serialNumber, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+		if err != nil {
+			return nil, err
+		}
+	}
+	notBefore := opts.NotBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now()
+	}
+	notAfter := opts.NotAfter
+	if notAfter.IsZero() {
+		notAfter = notBefore.Add(defaultCertificateValidity)
+	}
+	keyUsage := opts.KeyUsage
+	if keyUsage == 0 {
+		if isCA {
+			keyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+		} else {
+			keyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+		}
+	}
+	return &x509.Certificate{
+		// SM2WithSM3 时 gmsm 直接对TBS签名(含ZA),其他取值会先做一次摘要
+		SignatureAlgorithm:    x509.SM2WithSM3,
+		SerialNumber:          serialNumber,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           opts.ExtKeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		MaxPathLen:            opts.MaxPathLen,
+	}, nil
+}
+
+// subjectKeyID 取非压缩公钥SM3摘要的前20字节作为密钥标识
+func subjectKeyID(publicKey *sm2.PublicKey) []byte {
+	return sm3.Sm3Sum(EncodePublicKey(publicKey))[:20]
+}
+
+// certificatePublicKey 取出证书中的SM2公钥
+func certificatePublicKey(cert *x509.Certificate) (*sm2.PublicKey, error) {
+	if cert == nil {
+		return nil, fmt.Errorf("%w: nil certificate", ErrInvalidCertificate)
+	}
+	return sm2PublicKeyOf(cert.PublicKey)
+}
+
+// sm2PublicKeyOf 将 gmsm x509 解析出的公钥转换为 sm2.PublicKey
+// gmsm 对 id-ecPublicKey+SM2曲线 的证书返回 *ecdsa.PublicKey
+func sm2PublicKeyOf(pub any) (*sm2.PublicKey, error) {
+	var publicKey *sm2.PublicKey
+	switch pub := pub.(type) {
+	case *sm2.PublicKey:
+		publicKey = &sm2.PublicKey{Curve: sm2.P256Sm2(), X: pub.X, Y: pub.Y}
+	case *ecdsa.PublicKey:
+		if pub.Curve != sm2.P256Sm2() {
+			return nil, fmt.Errorf("%w: not an SM2 public key", ErrUnsupportedPublicKey)
+		}
+		publicKey = &sm2.PublicKey{Curve: sm2.P256Sm2(), X: pub.X, Y: pub.Y}
+	default:
+		return nil, fmt.Errorf("%w: not an SM2 public key", ErrUnsupportedPublicKey)
+	}
+	if err := validatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+	return publicKey, nil
+}
@@ -0,0 +1,154 @@
+package encryption
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/tjfoc/gmsm/sm2"
+)
+
+// CipherFormat SM2密文格式
+// C1 为随机点(X||Y,各32字节),C3 为SM3杂凑值(32字节),C2 为与明文等长的密文
+type CipherFormat int
+
+const (
+	// CipherC1C3C2 04||C1||C3||C2,GM/T 0003 新版标准格式
+	CipherC1C3C2 CipherFormat = iota
+	// CipherC1C2C3 04||C1||C2||C3,旧版标准格式
+	CipherC1C2C3
+	// CipherC1C3C2NoPrefix C1||C3||C2,C1不带04前缀(部分java客户端)
+	CipherC1C3C2NoPrefix
+	// CipherC1C2C3NoPrefix C1||C2||C3,C1不带04前缀(部分java客户端)
+	CipherC1C2C3NoPrefix
+	// CipherASN1 GM/T 0009 定义的 ASN.1 DER 编码 SM2Cipher 结构
+	CipherASN1
+)
+
+// ErrInvalidCiphertext 密文长度或结构与声明的格式不符
+var ErrInvalidCiphertext = errors.New("sm2: invalid ciphertext")
+
+// sm2Cipher GM/T 0009 SM2Cipher ::= SEQUENCE { XCoordinate INTEGER, YCoordinate INTEGER, HASH OCTET STRING, CipherText OCTET STRING }
+type sm2Cipher struct {
+	XCoordinate *big.Int
+	YCoordinate *big.Int
+	HASH        []byte
+	CipherText  []byte
+}
+
+// sm2CipherParts 拆分后的密文分量
+type sm2CipherParts struct {
+	c1 []byte // X||Y
+	c3 []byte
+	c2 []byte
+}
+
+// ConvertCiphertext 在不同SM2密文格式之间转换
+// ciphertext 密文
+// from 输入密文格式
+// to 输出密文格式
+func ConvertCiphertext(ciphertext []byte, from, to CipherFormat) ([]byte, error) {
+	parts, err := splitCiphertext(ciphertext, from)
+	if err != nil {
+		return nil, err
+	}
+	return joinCiphertext(parts, to)
+}
+
+// splitCiphertext 按格式拆分密文为 C1、C3、C2
+func splitCiphertext(ciphertext []byte, format CipherFormat) (*sm2CipherParts, error) {
+	byteLen := sm2ByteLen()
+	c1Len := 2 * byteLen
+	c3Len := 32
+	switch format {
+	case CipherC1C3C2, CipherC1C2C3:
+		if len(ciphertext) == 0 || ciphertext[0] != 0x04 {
+			return nil, fmt.Errorf("%w: missing 0x04 prefix", ErrInvalidCiphertext)
+		}
+		ciphertext = ciphertext[1:]
+	case CipherC1C3C2NoPrefix, CipherC1C2C3NoPrefix:
+	case CipherASN1:
+		return splitASN1Ciphertext(ciphertext)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %d", ErrInvalidCiphertext, format)
+	}
+	if len(ciphertext) < c1Len+c3Len {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidCiphertext)
+	}
+	x := new(big.Int).SetBytes(ciphertext[:byteLen])
+	y := new(big.Int).SetBytes(ciphertext[byteLen:c1Len])
+	if !sm2.P256Sm2().IsOnCurve(x, y) {
+		return nil, fmt.Errorf("%w: C1 is not on the curve", ErrInvalidCiphertext)
+	}
+	parts := &sm2CipherParts{c1: ciphertext[:c1Len]}
+	rest := ciphertext[c1Len:]
+	switch format {
+	case CipherC1C3C2, CipherC1C3C2NoPrefix:
+		parts.c3 = rest[:c3Len]
+		parts.c2 = rest[c3Len:]
+	default:
+		parts.c2 = rest[:len(rest)-c3Len]
+		parts.c3 = rest[len(rest)-c3Len:]
+	}
+	return parts, nil
+}
+
+// splitASN1Ciphertext 解析 ASN.1 DER 编码的 SM2Cipher
+func splitASN1Ciphertext(ciphertext []byte) (*sm2CipherParts, error) {
+	var cipher sm2Cipher
+	rest, err := asn1.Unmarshal(ciphertext, &cipher)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data after SM2Cipher", ErrInvalidCiphertext)
+	}
+	p := sm2.P256Sm2().Params().P
+	if cipher.XCoordinate.Sign() < 0 || cipher.XCoordinate.Cmp(p) >= 0 ||
+		cipher.YCoordinate.Sign() < 0 || cipher.YCoordinate.Cmp(p) >= 0 {
+		return nil, fmt.Errorf("%w: C1 coordinate out of range", ErrInvalidCiphertext)
+	}
+	if !sm2.P256Sm2().IsOnCurve(cipher.XCoordinate, cipher.YCoordinate) {
+		return nil, fmt.Errorf("%w: C1 is not on the curve", ErrInvalidCiphertext)
+	}
+	if len(cipher.HASH) != 32 {
+		return nil, fmt.Errorf("%w: hash must be 32 bytes", ErrInvalidCiphertext)
+	}
+	byteLen := sm2ByteLen()
+	c1 := make([]byte, 2*byteLen)
+	cipher.XCoordinate.FillBytes(c1[:byteLen])
+	cipher.YCoordinate.FillBytes(c1[byteLen:])
+	return &sm2CipherParts{c1: c1, c3: cipher.HASH, c2: cipher.CipherText}, nil
+}
+
+// joinCiphertext 按格式拼接密文分量
+func joinCiphertext(parts *sm2CipherParts, format CipherFormat) ([]byte, error) {
+	if format == CipherASN1 {
+		byteLen := sm2ByteLen()
+		return asn1.Marshal(sm2Cipher{
+			XCoordinate: new(big.Int).SetBytes(parts.c1[:byteLen]),
+			YCoordinate: new(big.Int).SetBytes(parts.c1[byteLen:]),
+			HASH:        parts.c3,
+			CipherText:  parts.c2,
+		})
+	}
+	ciphertext := make([]byte, 0, 1+len(parts.c1)+len(parts.c3)+len(parts.c2))
+	switch format {
+	case CipherC1C3C2, CipherC1C2C3:
+		ciphertext = append(ciphertext, 0x04)
+	case CipherC1C3C2NoPrefix, CipherC1C2C3NoPrefix:
+	default:
+		return nil, fmt.Errorf("%w: unknown format %d", ErrInvalidCiphertext, format)
+	}
+	ciphertext = append(ciphertext, parts.c1...)
+	switch format {
+	case CipherC1C3C2, CipherC1C3C2NoPrefix:
+		ciphertext = append(ciphertext, parts.c3...)
+		ciphertext = append(ciphertext, parts.c2...)
+	default:
+		ciphertext = append(ciphertext, parts.c2...)
+		ciphertext = append(ciphertext, parts.c3...)
+	}
+	return ciphertext, nil
+}
@@ -0,0 +1,207 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/tjfoc/gmsm/sm2"
+	"github.com/tjfoc/gmsm/sm3"
+)
+
+var (
+	// ErrKeyExchange 密钥协商参数错误或计算失败
+	ErrKeyExchange = errors.New("sm2: key exchange failed")
+	// ErrKeyConfirmation 对方的确认值(S1/S2)校验失败
+	ErrKeyConfirmation = errors.New("sm2: key confirmation failed")
+)
+
+// KeyExchange GM/T 0003.3 SM2密钥协商的一方
+//
+// 协商流程(A为发起方,B为响应方):
+//
+//	A: RA, _ := a.Init()                  // 发送 RA
+//	B: RB, SB, _ := b.Respond(RA)         // 发送 RB、SB,B 已可调用 Key()
+//	A: SA, _ := a.Finish(RB, SB)          // 校验 SB,发送 SA,A 已可调用 Key()
+//	B: _ = b.Confirm(SA)                  // 校验 SA
+//
+// 确认步骤可选:A 调用 Finish 时 SB 传nil即跳过校验,B 可不调用 Confirm。
+// 双方的用户标识取自 SetUserID,未设置时使用 DefaultUserID。
+type KeyExchange struct {
+	self      *SM2
+	peer      *SM2
+	initiator bool
+	keyLen    int
+
+	ephemeral *sm2.PrivateKey
+	key       []byte
+	// confirmation 本方待校验的对方确认值
+	confirmation []byte
+}
+
+// NewKeyExchangeInitiator 创建密钥协商发起方(A)
+// peer 响应方,只需持有公钥
+// keyLen 协商密钥字节长度
+func (enc *SM2) NewKeyExchangeInitiator(peer *SM2, keyLen int) (*KeyExchange, error) {
+	return newKeyExchange(enc, peer, keyLen, true)
+}
+
+// NewKeyExchangeResponder 创建密钥协商响应方(B)
+// peer 发起方,只需持有公钥
+// keyLen 协商密钥字节长度
+func (enc *SM2) NewKeyExchangeResponder(peer *SM2, keyLen int) (*KeyExchange, error) {
+	return newKeyExchange(enc, peer, keyLen, false)
+}
+
+func newKeyExchange(self, peer *SM2, keyLen int, initiator bool) (*KeyExchange, error) {
+	if self.privateKey == nil {
+		return nil, ErrPrivateKeyRequired
+	}
+	if peer == nil || peer.publicKey == nil {
+		return nil, ErrPublicKeyRequired
+	}
+	if keyLen <= 0 {
+		return nil, fmt.Errorf("%w: key length must be positive", ErrKeyExchange)
+	}
+	ephemeral, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newKeyExchangeWithEphemeral(self, peer, keyLen, initiator, ephemeral), nil
+}
+
+// newKeyExchangeWithEphemeral 使用指定的临时密钥 rA/rB 创建协商方,供已知答案测试复现标准流程
+func newKeyExchangeWithEphemeral(self, peer *SM2, keyLen int, initiator bool, ephemeral *sm2.PrivateKey) *KeyExchange {
+	return &KeyExchange{self: self, peer: peer, initiator: initiator, keyLen: keyLen, ephemeral: ephemeral}
+}
+
+// Init 发起方生成临时公钥 RA(非压缩格式 04||X||Y),发送给响应方
+func (kx *KeyExchange) Init() ([]byte, error) {
+	if !kx.initiator {
+		return nil, fmt.Errorf("%w: Init must be called by the initiator", ErrKeyExchange)
+	}
+	return EncodePublicKey(&kx.ephemeral.PublicKey), nil
+}
+
+// Respond 响应方收到 RA 后计算协商密钥,返回临时公钥 RB 及确认值 SB
+// ra 发起方临时公钥
+func (kx *KeyExchange) Respond(ra []byte) (rb, sb []byte, err error) {
+	if kx.initiator {
+		return nil, nil, fmt.Errorf("%w: Respond must be called by the responder", ErrKeyExchange)
+	}
+	sb, sa, err := kx.compute(ra)
+	if err != nil {
+		return nil, nil, err
+	}
+	kx.confirmation = sa
+	return EncodePublicKey(&kx.ephemeral.PublicKey), sb, nil
+}
+
+// Finish 发起方收到 RB 后计算协商密钥,并返回发送给响应方的确认值 SA
+// rb 响应方临时公钥
+// sb 响应方确认值,为nil时跳过校验
+func (kx *KeyExchange) Finish(rb, sb []byte) (sa []byte, err error) {
+	if !kx.initiator {
+		return nil, fmt.Errorf("%w: Finish must be called by the initiator", ErrKeyExchange)
+	}
+	sa, s1, err := kx.compute(rb)
+	if err != nil {
+		return nil, err
+	}
+	if sb != nil && subtle.ConstantTimeCompare(s1, sb) != 1 {
+		kx.key = nil
+		return nil, ErrKeyConfirmation
+	}
+	return sa, nil
+}
+
+// Confirm 响应方校验发起方的确认值 SA
+func (kx *KeyExchange) Confirm(sa []byte) error {
+	if kx.initiator || kx.confirmation == nil {
+		return fmt.Errorf("%w: Confirm must be called by the responder after Respond", ErrKeyExchange)
+	}
+	if subtle.ConstantTimeCompare(kx.confirmation, sa) != 1 {
+		return ErrKeyConfirmation
+	}
+	return nil
+}
+
+// Key 返回协商得到的共享密钥,协商完成前返回nil
+func (kx *KeyExchange) Key() []byte {
+	return kx.key
+}
+
+// compute 根据对方临时公钥计算共享点 U/V、协商密钥及双方确认值
+// 返回值 toPeer 为本方发出的确认值,fromPeer 为期望对方发来的确认值
+func (kx *KeyExchange) compute(peerEphemeral []byte) (toPeer, fromPeer []byte, err error) {
+	rp, err := decodePublicKeyBytes(peerEphemeral)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrKeyExchange, err)
+	}
+	curve := sm2.P256Sm2()
+	n := curve.Params().N
+
+	// t = (d + x̄·r) mod n
+	t := new(big.Int).Mul(keyExchangeXHat(kx.ephemeral.X), kx.ephemeral.D)
+	t.Add(t, kx.self.privateKey.D)
+	t.Mod(t, n)
+
+	// U = [t](P + [x̄p]Rp),余因子 h=1
+	x, y := curve.ScalarMult(rp.X, rp.Y, keyExchangeXHat(rp.X).Bytes())
+	x, y = curve.Add(kx.peer.publicKey.X, kx.peer.publicKey.Y, x, y)
+	ux, uy := curve.ScalarMult(x, y, t.Bytes())
+	if ux.Sign() == 0 && uy.Sign() == 0 {
+		return nil, nil, fmt.Errorf("%w: shared point is at infinity", ErrKeyExchange)
+	}
+
+	selfZ, err := sm2.ZA(kx.self.publicKey, kx.self.getUserID())
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrKeyExchange, err)
+	}
+	peerZ, err := sm2.ZA(kx.peer.publicKey, kx.peer.getUserID())
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrKeyExchange, err)
+	}
+	za, zb := selfZ, peerZ
+	ra, rb := &kx.ephemeral.PublicKey, rp
+	if !kx.initiator {
+		za, zb = peerZ, selfZ
+		ra, rb = rp, &kx.ephemeral.PublicKey
+	}
+
+	byteLen := sm2ByteLen()
+	xu := make([]byte, byteLen)
+	yu := make([]byte, byteLen)
+	ux.FillBytes(xu)
+	uy.FillBytes(yu)
+	if kx.key, err = SM3KDF(kx.keyLen, xu, yu, za, zb); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrKeyExchange, err)
+	}
+
+	// Hash(xU||ZA||ZB||x1||y1||x2||y2)
+	h := sm3.New()
+	h.Write(xu)
+	h.Write(za)
+	h.Write(zb)
+	h.Write(EncodePublicKey(ra)[1:])
+	h.Write(EncodePublicKey(rb)[1:])
+	inner := h.Sum(nil)
+
+	// S1/SB 以0x02开头,S2/SA 以0x03开头
+	s02 := sm3.Sm3Sum(append(append([]byte{0x02}, yu...), inner...))
+	s03 := sm3.Sm3Sum(append(append([]byte{0x03}, yu...), inner...))
+	if kx.initiator {
+		return s03, s02, nil
+	}
+	return s02, s03, nil
+}
+
+// keyExchangeXHat 计算 x̄ = 2^w + (x & (2^w-1)),w = 127
+func keyExchangeXHat(x *big.Int) *big.Int {
+	twoW := new(big.Int).Lsh(big.NewInt(1), 127)
+	xHat := new(big.Int).Sub(twoW, big.NewInt(1))
+	xHat.And(xHat, x)
+	return xHat.Add(xHat, twoW)
+}
@@ -0,0 +1,121 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/tjfoc/gmsm/sm2"
+)
+
+// Ephemeral keys can only be injected from inside the package, so this known-answer
+// test lives here rather than under test/.
+//
+// The GM/T 0003 Annex A key exchange example uses the Fp-256 test curve, while this
+// package is fixed to the recommended curve. These vectors are on the recommended curve:
+// private keys and shared keys come from sm2_keyexchange_test.go in
+// github.com/emmansun/gmsm v0.15.5, and RA/RB/SB/SA were computed by that independent
+// implementation from the same inputs. User IDs are "Alice" for A and "Bob" for B; keys are 48 bytes.
+var keyExchangeVectors = []struct {
+	dA, rA, dB, rB string
+	ra, rb, sb, sa string
+	key            string
+}{
+	{
+		dA:  "e04c3fd77408b56a648ad439f673511a2ae248def3bab26bdfc9cdbd0ae9607e",
+		rA:  "6fe0bac5b09d3ab10f724638811c34464790520e4604e71e6cb0e5310623b5b1",
+		dB:  "7a1136f60d2c5531447e5a3093078c2a505abf74f33aefed927ac0a5b27e7dd7",
+		rB:  "d0233bdbb0b8a7bfe1aab66132ef06fc4efaedd5d5000692bc21185242a31f6f",
+		ra:  "048d47233f429040d2039719ec7972fad4b20dd26db6dc12a21b1d435e99d48e51f9a2e058c12de189bd08b040d9bf8887f6e0d04abdf80fa5b8005c8131069a99",
+		rb:  "044944390ed8f6f52b4303bae82017416236cfe010373c9c2dc5ad00abbfedc55326b730baf840478b6621deccafc9aa4199a476bf7b58d23a270bfd8fce91ef31",
+		sb:  "0227e5336ba948ff95372ce925c4d8ebdf1384ddf563374351e0053b5fbadc87",
+		sa:  "822dad808df9f31f6e1667ad372689f1b499841ceb36c4fa68c2ea69e9edfd88",
+		key: "1ad809ebc56ddda532020c352e1e60b121ebeb7b4e632db4dd90a362cf844f8bba85140e30984ddb581199bf5a9dda22",
+	},
+	{
+		dA:  "cb5ac204b38d0e5c9fc38a467075986754018f7dbb7cbbc5b4c78d56a88a8ad8",
+		rA:  "1681a66c02b67fdadfc53cba9b417b9499d0159435c86bb8760c3a03ae157539",
+		dB:  "4f54b10e0d8e9e2fe5cc79893e37fd0fd990762d1372197ed92dde464b2773ef",
+		rB:  "a2fe43dea141e9acc88226eaba8908ad17e81376c92102cb8186e8fef61a8700",
+		ra:  "04a67a15e1e30e246755c91923547ce95727b8edf4cd578c3acffb9db38f1257bfe14ffe67646527caf99fe1354f2d9de2bab867dc045dc70969447d16a8b637c1",
+		rb:  "049abcf41b419a368a3fc2499c28f012be9e2ac9993166b593a2d87de5fafccad3e708b0e7aee761be284e0b76aea1f1220ecb87738828e9e0d2dec39adbdaf0b8",
+		sb:  "14fbc6203755cf19303b10bfb7af1a92cd6e2be354b4510c83434af7523e8fc4",
+		sa:  "c036bc3b1d403478d9e31f045dc30fd6504913b73c6b9a9da756f5851f0b9a43",
+		key: "7a103ae61a30ed9df573a5febb35a9609cbed5681bcb98a8545351bf7d6824cc4635df5203712ea506e2e3c4ec9b12e7",
+	},
+	{
+		dA:  "ee690a34a779ab48227a2f68b062a80f92e26d82835608dd01b7452f1e4fb296",
+		rA:  "2046c6cee085665e9f3abeba41fd38e17a26c08f2f5e8f0e1007afc0bf6a2a5d",
+		dB:  "8ef49ea427b13cc31151e1c96ae8a48cb7919063f2d342560fb7eaaffb93d8fe",
+		rB:  "9baf8d602e43fbae83fedb7368f98c969d378b8a647318f8cafb265296ae37de",
+		ra:  "0479aa0b72c897011fd07a74ff8e7945533384ea1ad26bfe1ebb4d60e79b8e6f22b55b8e5a79fd72d8ebc4b55c0013a76d099319f6a59e4784cc79c2738ec9e7e1",
+		rb:  "04b45c109d160bf9a3995e2f60f81d1cd544471e16d3066d1d17c83a9850ea29e9ca617b3747b239aa910b997c4dc757bac4cd8f8f7bd7ef316289a3c6fede2372",
+		sb:  "28e0ae02e350866a269c85f45a922784aeac4c357e156a33bb4af1b2c58b4d46",
+		sa:  "471fee6a40eb7ffd1ae9ec14d50cf2ffdaeb8ff6d74a7d0b23cd2f329b20897f",
+		key: "b18e78e5072f301399dc1f4baf2956c0ed2d5f52f19abb1705131b0865b079031259ee6c629b4faed528bcfa1c5d2cbc",
+	},
+}
+
+func TestKeyExchangeKnownAnswer(t *testing.T) {
+	for i, v := range keyExchangeVectors {
+		a := keyExchangeParty(t, v.dA, "Alice")
+		b := keyExchangeParty(t, v.dB, "Bob")
+		aPeer := &SM2{publicKey: b.publicKey}
+		aPeer.SetUserID([]byte("Bob"))
+		bPeer := &SM2{publicKey: a.publicKey}
+		bPeer.SetUserID([]byte("Alice"))
+
+		initiator := newKeyExchangeWithEphemeral(a, aPeer, 48, true, keyExchangeEphemeral(t, v.rA))
+		responder := newKeyExchangeWithEphemeral(b, bPeer, 48, false, keyExchangeEphemeral(t, v.rB))
+
+		ra, err := initiator.Init()
+		if err != nil {
+			t.Fatalf("vector %d: Init failed: %v", i, err)
+		}
+		expectHex(t, i, "RA", ra, v.ra)
+		rb, sb, err := responder.Respond(ra)
+		if err != nil {
+			t.Fatalf("vector %d: Respond failed: %v", i, err)
+		}
+		expectHex(t, i, "RB", rb, v.rb)
+		expectHex(t, i, "SB", sb, v.sb)
+		sa, err := initiator.Finish(rb, sb)
+		if err != nil {
+			t.Fatalf("vector %d: Finish failed: %v", i, err)
+		}
+		expectHex(t, i, "SA", sa, v.sa)
+		if err := responder.Confirm(sa); err != nil {
+			t.Fatalf("vector %d: Confirm failed: %v", i, err)
+		}
+		expectHex(t, i, "KA", initiator.Key(), v.key)
+		expectHex(t, i, "KB", responder.Key(), v.key)
+	}
+}
+
+func keyExchangeParty(t *testing.T, d, userID string) *SM2 {
+	t.Helper()
+	party, err := NewSM2Decryptor(d)
+	if err != nil {
+		t.Fatalf("NewSM2Decryptor failed: %v", err)
+	}
+	party.SetUserID([]byte(userID))
+	return party
+}
+
+func keyExchangeEphemeral(t *testing.T, r string) *sm2.PrivateKey {
+	t.Helper()
+	d, _ := new(big.Int).SetString(r, 16)
+	ephemeral, err := derivePrivateKey(d)
+	if err != nil {
+		t.Fatalf("derivePrivateKey failed: %v", err)
+	}
+	return ephemeral
+}
+
+func expectHex(t *testing.T, vector int, name string, got []byte, want string) {
+	t.Helper()
+	if expected, _ := hex.DecodeString(want); !bytes.Equal(got, expected) {
+		t.Errorf("vector %d: %s mismatch. Expected: %s, Got: %x", vector, name, want, got)
+	}
+}
@@ -0,0 +1,174 @@
+package encryption
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/tjfoc/gmsm/sm2"
+	"github.com/tjfoc/gmsm/x509"
+)
+
+const (
+	pemTypePublicKey           = "PUBLIC KEY"
+	pemTypePrivateKey          = "PRIVATE KEY"
+	pemTypeEncryptedPrivateKey = "ENCRYPTED PRIVATE KEY"
+	pemTypeECPrivateKey        = "EC PRIVATE KEY"
+	pemTypeSM2PrivateKey       = "SM2 PRIVATE KEY"
+	pemTypeECParameters        = "EC PARAMETERS"
+)
+
+// NewSM2FromPEM 从PEM格式私钥创建SM2,公钥由私钥推导
+// 支持 PKCS#8(PRIVATE KEY)、加密PKCS#8(ENCRYPTED PRIVATE KEY) 以及 OpenSSL/Tongsuo 的 SEC1(EC/SM2 PRIVATE KEY) 格式
+// privateKeyPEM PEM格式私钥
+// password 加密私钥的口令,未加密时传nil
+func NewSM2FromPEM(privateKeyPEM, password []byte) (*SM2, error) {
+	privateKey, err := DecodePrivateKeyPEM(privateKeyPEM, password)
+	if err != nil {
+		return nil, err
+	}
+	return &SM2{publicKey: &privateKey.PublicKey, privateKey: privateKey}, nil
+}
+
+// NewSM2EncryptorFromPEM 从PEM格式(SubjectPublicKeyInfo)公钥创建仅持有公钥的SM2
+func NewSM2EncryptorFromPEM(publicKeyPEM []byte) (*SM2, error) {
+	publicKey, err := DecodePublicKeyPEM(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &SM2{publicKey: publicKey}, nil
+}
+
+// NewSM2FromDER 从DER格式PKCS#8私钥创建SM2,公钥由私钥推导
+// privateKeyDER DER格式PKCS#8私钥
+// password 加密私钥的口令,未加密时传nil
+func NewSM2FromDER(privateKeyDER, password []byte) (*SM2, error) {
+	privateKey, err := DecodePrivateKeyDER(privateKeyDER, password)
+	if err != nil {
+		return nil, err
+	}
+	return &SM2{publicKey: &privateKey.PublicKey, privateKey: privateKey}, nil
+}
+
+// DecodePublicKeyPEM 将PEM格式(SubjectPublicKeyInfo)公钥还原为 sm2.PublicKey 对象
+func DecodePublicKeyPEM(publicKeyPEM []byte) (*sm2.PublicKey, error) {
+	block, err := decodePEMBlock(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != pemTypePublicKey {
+		return nil, errors.New("sm2: unexpected PEM type " + block.Type)
+	}
+	return DecodePublicKeyDER(block.Bytes)
+}
+
+// DecodePublicKeyDER 将DER格式(SubjectPublicKeyInfo)公钥还原为 sm2.PublicKey 对象
+func DecodePublicKeyDER(publicKeyDER []byte) (*sm2.PublicKey, error) {
+	publicKey, err := x509.ParseSm2PublicKey(publicKeyDER)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+	if err := validatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+	return publicKey, nil
+}
+
+// DecodePrivateKeyPEM 将PEM格式私钥还原为 sm2.PrivateKey 对象
+// privateKeyPEM PEM格式私钥
+// password 加密私钥的口令,未加密时传nil
+func DecodePrivateKeyPEM(privateKeyPEM, password []byte) (*sm2.PrivateKey, error) {
+	block, err := decodePEMBlock(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	var privateKey *sm2.PrivateKey
+	switch block.Type {
+	case pemTypePrivateKey:
+		privateKey, err = x509.ParsePKCS8UnecryptedPrivateKey(block.Bytes)
+	case pemTypeEncryptedPrivateKey:
+		if password == nil {
+			return nil, errors.New("sm2: password required for encrypted private key")
+		}
+		privateKey, err = x509.ParsePKCS8EcryptedPrivateKey(block.Bytes, password)
+	case pemTypeECPrivateKey, pemTypeSM2PrivateKey:
+		privateKey, err = x509.ParseSm2PrivateKey(block.Bytes)
+	default:
+		return nil, errors.New("sm2: unexpected PEM type " + block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	if err := validatePrivateKey(privateKey); err != nil {
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+// DecodePrivateKeyDER 将DER格式PKCS#8私钥还原为 sm2.PrivateKey 对象
+// privateKeyDER DER格式PKCS#8私钥
+// password 加密私钥的口令,未加密时传nil
+func DecodePrivateKeyDER(privateKeyDER, password []byte) (*sm2.PrivateKey, error) {
+	var privateKey *sm2.PrivateKey
+	var err error
+	if password == nil {
+		privateKey, err = x509.ParsePKCS8UnecryptedPrivateKey(privateKeyDER)
+	} else {
+		privateKey, err = x509.ParsePKCS8EcryptedPrivateKey(privateKeyDER, password)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	if err := validatePrivateKey(privateKey); err != nil {
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+// PublicKeyPEM 导出PEM格式(SubjectPublicKeyInfo)公钥
+func (enc *SM2) PublicKeyPEM() ([]byte, error) {
+	if enc.publicKey == nil {
+		return nil, ErrPublicKeyRequired
+	}
+	return x509.WritePublicKeyToPem(enc.publicKey)
+}
+
+// PublicKeyDER 导出DER格式(SubjectPublicKeyInfo)公钥
+func (enc *SM2) PublicKeyDER() ([]byte, error) {
+	if enc.publicKey == nil {
+		return nil, ErrPublicKeyRequired
+	}
+	return x509.MarshalSm2PublicKey(enc.publicKey)
+}
+
+// PrivateKeyPEM 导出PEM格式PKCS#8私钥
+// password 非nil时导出为加密PKCS#8(PBES2/PBKDF2/AES-256-CBC)
+func (enc *SM2) PrivateKeyPEM(password []byte) ([]byte, error) {
+	if enc.privateKey == nil {
+		return nil, ErrPrivateKeyRequired
+	}
+	return x509.WritePrivateKeyToPem(enc.privateKey, password)
+}
+
+// PrivateKeyDER 导出DER格式PKCS#8私钥
+// password 非nil时导出为加密PKCS#8(PBES2/PBKDF2/AES-256-CBC)
+func (enc *SM2) PrivateKeyDER(password []byte) ([]byte, error) {
+	if enc.privateKey == nil {
+		return nil, ErrPrivateKeyRequired
+	}
+	return x509.MarshalSm2PrivateKey(enc.privateKey, password)
+}
+
+// decodePEMBlock 取出第一个密钥PEM块,跳过OpenSSL输出的 EC PARAMETERS 块
+func decodePEMBlock(data []byte) (*pem.Block, error) {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("sm2: failed to decode PEM block")
+		}
+		if block.Type != pemTypeECParameters {
+			return block, nil
+		}
+		data = rest
+	}
+}
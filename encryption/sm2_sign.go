@@ -0,0 +1,200 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/tjfoc/gmsm/sm2"
+)
+
+// DefaultUserID GM/T 0003 推荐的默认用户标识,参与ZA计算
+const DefaultUserID = "1234567812345678"
+
+// SignatureFormat 签名值编码格式
+type SignatureFormat int
+
+const (
+	// SignatureASN1 ASN.1 DER 编码的 SEQUENCE{r, s},与java BouncyCastle SM2Signer 默认输出一致
+	SignatureASN1 SignatureFormat = iota
+	// SignatureRaw r||s 各32字节拼接的64字节裸签名,常见于密码机/硬件厂商
+	SignatureRaw
+)
+
+// ErrInvalidSignature 签名格式错误或验签失败
+var ErrInvalidSignature = errors.New("sm2: invalid signature")
+
+type sm2Signature struct {
+	R, S *big.Int
+}
+
+// SetUserID 设置签名/验签使用的用户标识(ID),为空时使用 DefaultUserID
+func (enc *SM2) SetUserID(userID []byte) {
+	enc.userID = userID
+}
+
+// Sign 使用私钥对消息签名,签名过程包含ZA计算(GM/T 0003)
+// msg 待签名消息
+// format 签名值编码格式
+func (enc *SM2) Sign(msg []byte, format SignatureFormat) ([]byte, error) {
+	if enc.privateKey == nil {
+		return nil, ErrPrivateKeyRequired
+	}
+	r, s, err := sm2.Sm2Sign(enc.privateKey, msg, enc.getUserID(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return marshalSignature(r, s, format)
+}
+
+// Sign2Hex 签名并返回16进制字符串
+// msg 待签名消息
+// format 签名值编码格式
+func (enc *SM2) Sign2Hex(msg []byte, format SignatureFormat) (string, error) {
+	signature, err := enc.Sign(msg, format)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(signature), nil
+}
+
+// Sign2Base64 签名并返回Base64字符串
+// msg 待签名消息
+// format 签名值编码格式
+func (enc *SM2) Sign2Base64(msg []byte, format SignatureFormat) (string, error) {
+	signature, err := enc.Sign(msg, format)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// SignObject 对JSON对象签名
+// obj 待签名对象
+// format 签名值编码格式
+func (enc *SM2) SignObject(obj any, format SignatureFormat) ([]byte, error) {
+	marshal, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return enc.Sign(marshal, format)
+}
+
+// Verify 使用公钥验签,验签失败返回 ErrInvalidSignature
+// msg 原始消息
+// signature 签名值
+// format 签名值编码格式
+func (enc *SM2) Verify(msg, signature []byte, format SignatureFormat) error {
+	if enc.publicKey == nil {
+		return ErrPublicKeyRequired
+	}
+	r, s, err := unmarshalSignature(signature, format)
+	if err != nil {
+		return err
+	}
+	if !sm2.Sm2Verify(enc.publicKey, msg, enc.getUserID(), r, s) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyHex 使用公钥验证16进制签名
+// msg 原始消息
+// signature 16进制签名字符串
+// format 签名值编码格式
+func (enc *SM2) VerifyHex(msg []byte, signature string, format SignatureFormat) error {
+	decodeByes, err := hex.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	return enc.Verify(msg, decodeByes, format)
+}
+
+// VerifyBase64 使用公钥验证Base64签名
+// msg 原始消息
+// signature Base64签名字符串
+// format 签名值编码格式
+func (enc *SM2) VerifyBase64(msg []byte, signature string, format SignatureFormat) error {
+	decodeByes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	return enc.Verify(msg, decodeByes, format)
+}
+
+// VerifyObject 验证JSON对象签名
+// obj 原始对象
+// signature 签名值
+// format 签名值编码格式
+func (enc *SM2) VerifyObject(obj any, signature []byte, format SignatureFormat) error {
+	marshal, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return enc.Verify(marshal, signature, format)
+}
+
+// ConvertSignature 在 ASN.1 DER 与 r||s 两种签名编码之间转换
+func ConvertSignature(signature []byte, from, to SignatureFormat) ([]byte, error) {
+	r, s, err := unmarshalSignature(signature, from)
+	if err != nil {
+		return nil, err
+	}
+	return marshalSignature(r, s, to)
+}
+
+func (enc *SM2) getUserID() []byte {
+	if len(enc.userID) == 0 {
+		return []byte(DefaultUserID)
+	}
+	return enc.userID
+}
+
+func marshalSignature(r, s *big.Int, format SignatureFormat) ([]byte, error) {
+	switch format {
+	case SignatureASN1:
+		return asn1.Marshal(sm2Signature{R: r, S: s})
+	case SignatureRaw:
+		byteLen := sm2ByteLen()
+		signature := make([]byte, 2*byteLen)
+		r.FillBytes(signature[:byteLen])
+		s.FillBytes(signature[byteLen:])
+		return signature, nil
+	default:
+		return nil, errors.New("sm2: unknown signature format")
+	}
+}
+
+func unmarshalSignature(signature []byte, format SignatureFormat) (r, s *big.Int, err error) {
+	switch format {
+	case SignatureASN1:
+		var sig sm2Signature
+		rest, err := asn1.Unmarshal(signature, &sig)
+		if err != nil || len(rest) != 0 || !validSignatureScalar(sig.R) || !validSignatureScalar(sig.S) {
+			return nil, nil, ErrInvalidSignature
+		}
+		return sig.R, sig.S, nil
+	case SignatureRaw:
+		byteLen := sm2ByteLen()
+		if len(signature) != 2*byteLen {
+			return nil, nil, ErrInvalidSignature
+		}
+		r = new(big.Int).SetBytes(signature[:byteLen])
+		s = new(big.Int).SetBytes(signature[byteLen:])
+		if !validSignatureScalar(r) || !validSignatureScalar(s) {
+			return nil, nil, ErrInvalidSignature
+		}
+		return r, s, nil
+	default:
+		return nil, nil, errors.New("sm2: unknown signature format")
+	}
+}
+
+// validSignatureScalar r、s 须在 [1, n-1] 范围内
+func validSignatureScalar(v *big.Int) bool {
+	return v != nil && v.Sign() > 0 && v.Cmp(sm2.P256Sm2().Params().N) < 0
+}
@@ -1,12 +1,90 @@
 package encryption
 
 import (
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+
 	"github.com/tjfoc/gmsm/sm3"
 )
 
-// EncodeToSM3 Encode To SM3
-func EncodeToSM3(publicKeyHex string) []byte {
-	h := sm3.New()
-	h.Write([]byte(publicKeyHex))
-	return h.Sum(nil)
+// SM3Size SM3杂凑值字节长度
+const SM3Size = 32
+
+// SM3 流式SM3杂凑,实现 hash.Hash 接口,可配合 io.Copy 处理大文件
+type SM3 struct {
+	h hash.Hash
+}
+
+var _ hash.Hash = (*SM3)(nil)
+
+// NewSM3 创建流式SM3杂凑
+func NewSM3() *SM3 {
+	return &SM3{h: sm3.New()}
+}
+
+// Write 追加数据,不会返回错误
+func (s *SM3) Write(p []byte) (int, error) {
+	return s.h.Write(p)
+}
+
+// Sum 将当前杂凑值追加到b后返回,不改变内部状态
+// gmsm 的 Sum(b) 会把b当作消息写入且只返回杂凑值,这里不将b传给它
+func (s *SM3) Sum(b []byte) []byte {
+	return append(b, s.h.Sum(nil)...)
+}
+
+// Sum2Hex 返回当前杂凑值的16进制字符串
+func (s *SM3) Sum2Hex() string {
+	return hex.EncodeToString(s.h.Sum(nil))
+}
+
+// Sum2Base64 返回当前杂凑值的Base64字符串
+func (s *SM3) Sum2Base64() string {
+	return base64.StdEncoding.EncodeToString(s.h.Sum(nil))
+}
+
+// Reset 重置为初始状态
+func (s *SM3) Reset() {
+	s.h.Reset()
+}
+
+// Size 杂凑值字节长度
+func (s *SM3) Size() int {
+	return SM3Size
+}
+
+// BlockSize 分组字节长度
+func (s *SM3) BlockSize() int {
+	return s.h.BlockSize()
+}
+
+// SM3Sum 计算数据的SM3杂凑值
+func SM3Sum(data []byte) []byte {
+	return sm3.Sm3Sum(data)
+}
+
+// SM3Sum2Hex 计算数据的SM3杂凑值并返回16进制字符串
+func SM3Sum2Hex(data []byte) string {
+	return hex.EncodeToString(SM3Sum(data))
+}
+
+// SM3Sum2Base64 计算数据的SM3杂凑值并返回Base64字符串
+func SM3Sum2Base64(data []byte) string {
+	return base64.StdEncoding.EncodeToString(SM3Sum(data))
+}
+
+// SM3SumReader 流式读取r直至EOF并计算SM3杂凑值,内存占用与数据大小无关
+func SM3SumReader(r io.Reader) ([]byte, error) {
+	h := NewSM3()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// EncodeToSM3 计算字符串的SM3杂凑值,大数据请使用 SM3SumReader 或 NewSM3
+func EncodeToSM3(data string) []byte {
+	return SM3Sum([]byte(data))
 }
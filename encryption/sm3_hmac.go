@@ -0,0 +1,86 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+)
+
+// ErrInvalidMAC 消息认证码格式错误或校验失败
+var ErrInvalidMAC = errors.New("hmac-sm3: invalid MAC")
+
+// NewHMACSM3 创建流式HMAC-SM3,实现 hash.Hash 接口
+// key 密钥,长度超过64字节时按HMAC规则先做SM3杂凑
+func NewHMACSM3(key []byte) hash.Hash {
+	return hmac.New(func() hash.Hash { return NewSM3() }, key)
+}
+
+// HMACSM3Sum 计算数据的HMAC-SM3消息认证码
+func HMACSM3Sum(key, data []byte) []byte {
+	mac := NewHMACSM3(key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// HMACSM3Sum2Hex 计算HMAC-SM3并返回16进制字符串
+func HMACSM3Sum2Hex(key, data []byte) string {
+	return hex.EncodeToString(HMACSM3Sum(key, data))
+}
+
+// HMACSM3Sum2Base64 计算HMAC-SM3并返回Base64字符串
+func HMACSM3Sum2Base64(key, data []byte) string {
+	return base64.StdEncoding.EncodeToString(HMACSM3Sum(key, data))
+}
+
+// HMACSM3SumReader 流式读取r直至EOF并计算HMAC-SM3
+func HMACSM3SumReader(key []byte, r io.Reader) ([]byte, error) {
+	mac := NewHMACSM3(key)
+	if _, err := io.Copy(mac, r); err != nil {
+		return nil, err
+	}
+	return mac.Sum(nil), nil
+}
+
+// VerifyHMACSM3 以常量时间比较校验消息认证码,不一致时返回 ErrInvalidMAC
+// key 密钥
+// data 原始数据
+// mac 待校验的消息认证码
+func VerifyHMACSM3(key, data, mac []byte) error {
+	if !hmac.Equal(HMACSM3Sum(key, data), mac) {
+		return ErrInvalidMAC
+	}
+	return nil
+}
+
+// VerifyHMACSM3Hex 校验16进制消息认证码
+func VerifyHMACSM3Hex(key, data []byte, mac string) error {
+	decodeBytes, err := hex.DecodeString(mac)
+	if err != nil {
+		return ErrInvalidMAC
+	}
+	return VerifyHMACSM3(key, data, decodeBytes)
+}
+
+// VerifyHMACSM3Base64 校验Base64消息认证码
+func VerifyHMACSM3Base64(key, data []byte, mac string) error {
+	decodeBytes, err := base64.StdEncoding.DecodeString(mac)
+	if err != nil {
+		return ErrInvalidMAC
+	}
+	return VerifyHMACSM3(key, data, decodeBytes)
+}
+
+// VerifyHMACSM3Reader 流式读取r并校验消息认证码,读取失败时返回读取错误
+func VerifyHMACSM3Reader(key []byte, r io.Reader, mac []byte) error {
+	sum, err := HMACSM3SumReader(key, r)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(sum, mac) {
+		return ErrInvalidMAC
+	}
+	return nil
+}
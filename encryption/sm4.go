@@ -3,26 +3,65 @@ package encryption
 import (
 	"bytes"
 	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/tjfoc/gmsm/sm4"
 )
 
+// sm4BlockSize SM4分组及密钥字节长度
+const sm4BlockSize = 16
+
+var (
+	// ErrSM4InvalidKey SM4密钥长度错误
+	ErrSM4InvalidKey = errors.New("sm4: invalid key")
+	// ErrSM4InvalidIV SM4 IV长度错误
+	ErrSM4InvalidIV = errors.New("sm4: invalid IV")
+	// ErrSM4InvalidCiphertext SM4密文长度或格式错误
+	ErrSM4InvalidCiphertext = errors.New("sm4: invalid ciphertext")
+	// ErrInvalidPadding PKCS#7填充错误,通常由密钥或IV错误、密文被篡改导致
+	ErrInvalidPadding = errors.New("sm4: invalid padding")
+)
+
+// SM4 SM4对称加密,默认CBC模式,可通过 WithMode 选择其他工作模式
+// 构造后只读,可在多个goroutine间共享
 type SM4 struct {
-	key []byte
-	iv  []byte
+	blocks   *sm4Pool[cipher.Block]
+	iv       []byte
+	mode     SM4Mode
+	randomIV bool
+	fixedIV  bool
 }
 
-// NewSM4 新建SM4
-func NewSM4(key, iv []byte) (sm2e *SM4, err error) {
-	sm2e = &SM4{key: key, iv: iv}
+// NewSM4 新建SM4,密钥或IV长度错误时返回 ErrSM4InvalidKey / ErrSM4InvalidIV
+// key 16字节密钥
+// iv 16字节IV,ECB模式或使用 WithRandomIV 时被忽略,可为nil
+// opts 构造选项,如 WithMode(ModeCTR)、WithRandomIV();流模式的IV要求见 WithFixedIV
+func NewSM4(key, iv []byte, opts ...SM4Option) (sm2e *SM4, err error) {
+	sm2e = &SM4{}
+	if err = sm2e.applyOptions(opts); err != nil {
+		return nil, err
+	}
+	if sm2e.mode != ModeECB && !sm2e.randomIV {
+		if len(iv) != sm4BlockSize {
+			return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrSM4InvalidIV, sm4BlockSize, len(iv))
+		}
+		sm2e.iv = bytes.Clone(iv)
+	}
+	if sm2e.blocks, err = newSM4Blocks(key); err != nil {
+		return nil, err
+	}
 	return
 }
 
 // FromHex 新建SM4
-func FromHex(key, iv string) (sm2e *SM4, err error) {
+func FromHex(key, iv string, opts ...SM4Option) (sm2e *SM4, err error) {
 	keyByts, err := hex.DecodeString(key)
 	if err != nil {
 		return nil, err
@@ -32,12 +71,11 @@ func FromHex(key, iv string) (sm2e *SM4, err error) {
 		return nil, err
 	}
 
-	sm2e = &SM4{key: keyByts, iv: ivByts}
-	return
+	return NewSM4(keyByts, ivByts, opts...)
 }
 
 // FromBase64 新建SM4
-func FromBase64(key, iv string) (sm2e *SM4, err error) {
+func FromBase64(key, iv string, opts ...SM4Option) (sm2e *SM4, err error) {
 	keyByts, err := base64.StdEncoding.DecodeString(key)
 	if err != nil {
 		return nil, err
@@ -47,21 +85,51 @@ func FromBase64(key, iv string) (sm2e *SM4, err error) {
 		return nil, err
 	}
 
-	sm2e = &SM4{key: keyByts, iv: ivByts}
-	return
+	return NewSM4(keyByts, ivByts, opts...)
 }
 
-// Decrypt 使用私钥对象解密密文字符串
+// Decrypt 使用私钥对象解密密文字符串,不修改ciphertext
+// CBC/ECB 模式下密文长度须为16的整数倍,否则返回 ErrSM4InvalidCiphertext;
+// 填充校验失败返回 ErrInvalidPadding,通常意味着密钥或IV错误、密文被篡改
 // ciphertext 待解密密文字符串
 func (enc *SM4) Decrypt(ciphertext []byte) ([]byte, error) {
-	block, err := sm4.NewCipher(enc.key)
+	return enc.DecryptInPlace(bytes.Clone(ciphertext))
+}
+
+// DecryptInPlace 原地解密,不额外分配内存
+// ciphertext 会被明文覆盖,返回值与其共享底层数组;解密失败时内容不确定
+func (enc *SM4) DecryptInPlace(ciphertext []byte) ([]byte, error) {
+	iv := enc.iv
+	if enc.randomIV {
+		if len(ciphertext) < sm4BlockSize {
+			return nil, fmt.Errorf("%w: missing IV prefix", ErrSM4InvalidCiphertext)
+		}
+		iv, ciphertext = ciphertext[:sm4BlockSize], ciphertext[sm4BlockSize:]
+	}
+	if !enc.mode.padded() {
+		block := enc.blocks.get()
+		defer enc.blocks.put(block)
+		enc.stream(block, iv, true).XORKeyStream(ciphertext, ciphertext)
+		return ciphertext, nil
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%sm4BlockSize != 0 {
+		return nil, fmt.Errorf("%w: length %d is not a multiple of the block size", ErrSM4InvalidCiphertext, len(ciphertext))
+	}
+	block := enc.blocks.get()
+	defer enc.blocks.put(block)
+	enc.blockMode(block, iv, true).CryptBlocks(ciphertext, ciphertext)
+	return UnpadPKCS7(ciphertext, sm4BlockSize)
+}
+
+// DecryptEncoded 解密指定编码的密文字符串
+// ciphertext 待解密密文字符串
+// encoding 密文编码,见 Encoding
+func (enc *SM4) DecryptEncoded(ciphertext string, encoding Encoding) ([]byte, error) {
+	decodeByes, err := encoding.Decode(ciphertext)
 	if err != nil {
 		return nil, err
 	}
-	blockMode := cipher.NewCBCDecrypter(block, enc.iv)
-	blockMode.CryptBlocks(ciphertext, ciphertext)
-	plainText := UnpaddingLastGroup(ciphertext)
-	return plainText, nil
+	return enc.DecryptInPlace(decodeByes)
 }
 
 // DecryptHex 使用私钥对象解密密Hex文字符串
@@ -71,7 +139,7 @@ func (enc *SM4) DecryptHex(ciphertext string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return enc.Decrypt(decodeByes)
+	return enc.DecryptInPlace(decodeByes)
 }
 
 // DecryptBase64 使用私钥对象解密密Base64文字符串
@@ -81,10 +149,11 @@ func (enc *SM4) DecryptBase64(ciphertext string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return enc.Decrypt(decodeByes)
+	return enc.DecryptInPlace(decodeByes)
 }
 
 // DecryptObject 使用私钥对象解密密文字符串
+// 密文须为16进制,JSON解码;其他格式使用 DecryptObjectEncoded
 // ciphertext 待解密密文字符串
 // obj 解码对象
 func (enc *SM4) DecryptObject(ciphertext string, obj any) error {
@@ -92,7 +161,7 @@ func (enc *SM4) DecryptObject(ciphertext string, obj any) error {
 	if err != nil {
 		return err
 	}
-	decrypt, err := enc.Decrypt(decodeByts)
+	decrypt, err := enc.DecryptInPlace(decodeByts)
 	if err != nil {
 		return err
 	}
@@ -102,17 +171,46 @@ func (enc *SM4) DecryptObject(ciphertext string, obj any) error {
 // Encrypt 加密
 // plaintext 待加密明文字符串
 func (enc *SM4) Encrypt(plaintext string) ([]byte, error) {
-	block, err := sm4.NewCipher(enc.key)
-	if err != nil {
-		return nil, err
+	return enc.EncryptBytes([]byte(plaintext))
+}
+
+// EncryptBytes 加密二进制数据,不修改plaintext
+// plaintext 待加密明文
+func (enc *SM4) EncryptBytes(plaintext []byte) ([]byte, error) {
+	iv, prefix := enc.iv, 0
+	if enc.randomIV {
+		iv, prefix = make([]byte, sm4BlockSize), sm4BlockSize
+		if _, err := rand.Read(iv); err != nil {
+			return nil, err
+		}
+	}
+	block := enc.blocks.get()
+	defer enc.blocks.put(block)
+	if !enc.mode.padded() {
+		cipherText := make([]byte, prefix+len(plaintext))
+		copy(cipherText, iv[:prefix])
+		enc.stream(block, iv, false).XORKeyStream(cipherText[prefix:], plaintext)
+		return cipherText, nil
 	}
-	paddData := PaddingLastGroup([]byte(plaintext), block.BlockSize())
-	blokMode := cipher.NewCBCEncrypter(block, enc.iv)
-	cipherText := make([]byte, len(paddData))
-	blokMode.CryptBlocks(cipherText, paddData)
+	// 在新缓冲区中填充,PaddingLastGroup 的 append 可能写入调用方切片的剩余容量
+	cipherText := make([]byte, prefix, prefix+len(plaintext)+sm4BlockSize)
+	copy(cipherText, iv[:prefix])
+	cipherText = PaddingLastGroup(append(cipherText, plaintext...), sm4BlockSize)
+	enc.blockMode(block, iv, false).CryptBlocks(cipherText[prefix:], cipherText[prefix:])
 	return cipherText, nil
 }
 
+// EncryptEncoded 加密并按指定编码返回密文字符串
+// plaintext 待加密明文
+// encoding 密文编码,见 Encoding
+func (enc *SM4) EncryptEncoded(plaintext []byte, encoding Encoding) (string, error) {
+	ciphertext, err := enc.EncryptBytes(plaintext)
+	if err != nil {
+		return "", err
+	}
+	return encoding.Encode(ciphertext)
+}
+
 // Encrypt2Hex 加密
 // plaintext 待加密明文字符串
 func (enc *SM4) Encrypt2Hex(plaintext string) (string, error) {
@@ -134,13 +232,39 @@ func (enc *SM4) Encrypt2Base64(plaintext string) (string, error) {
 }
 
 // EncryptObject 加密JSON对象
+// 返回原始密文字节;需要与 DecryptObject 对称时使用 EncryptObjectEncoded
 // obj 待加密对象
 func (enc *SM4) EncryptObject(obj any) ([]byte, error) {
 	marshal, err := json.Marshal(obj)
 	if err != nil {
 		return nil, err
 	}
-	return enc.Encrypt(string(marshal))
+	return enc.EncryptBytes(marshal)
+}
+
+// EncryptObjectEncoded 序列化并加密对象,按指定编码返回密文字符串,与 DecryptObjectEncoded 对称
+// obj 待加密对象
+// c 序列化方式,nil 为 JSONCodec
+// encoding 密文编码,见 Encoding
+func (enc *SM4) EncryptObjectEncoded(obj any, c Codec, encoding Encoding) (string, error) {
+	marshal, err := codecOrDefault(c).Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return enc.EncryptEncoded(marshal, encoding)
+}
+
+// DecryptObjectEncoded 解密 EncryptObjectEncoded 的输出并反序列化到对象
+// ciphertext 待解密密文字符串
+// c 序列化方式,须与加密时一致,nil 为 JSONCodec
+// encoding 密文编码,须与加密时一致
+// obj 解码对象
+func (enc *SM4) DecryptObjectEncoded(ciphertext string, c Codec, encoding Encoding, obj any) error {
+	decrypt, err := enc.DecryptEncoded(ciphertext, encoding)
+	if err != nil {
+		return err
+	}
+	return codecOrDefault(c).Unmarshal(decrypt, obj)
 }
 
 // PaddingLastGroup 明文数据填充
@@ -156,7 +280,33 @@ func PaddingLastGroup(plaintext []byte, blockSize int) []byte {
 	return newText
 }
 
+// UnpadPKCS7 校验并去除PKCS#7填充
+// 以常量时间检查最后一个分组中的全部填充字节,填充非法时返回 ErrInvalidPadding
+// 未认证的CBC密文只要能区分填充错误即构成填充预言(padding oracle),
+// 密文可能来自攻击者时应使用 SM4AEAD
+// plainText 解密后的数据,长度须为blockSize的整数倍
+// blockSize 分组字节长度,1~255
+func UnpadPKCS7(plainText []byte, blockSize int) ([]byte, error) {
+	length := len(plainText)
+	if blockSize <= 0 || blockSize > 255 || length == 0 || length%blockSize != 0 {
+		return nil, ErrInvalidPadding
+	}
+	padNum := int(plainText[length-1])
+	good := subtle.ConstantTimeLessOrEq(1, padNum) & subtle.ConstantTimeLessOrEq(padNum, blockSize)
+	lastGroup := plainText[length-blockSize:]
+	for i, b := range lastGroup {
+		// 距末尾 blockSize-i 个字节,处于填充范围内时必须等于 padNum
+		inPadding := subtle.ConstantTimeLessOrEq(blockSize-i, padNum)
+		good &= (inPadding ^ 1) | subtle.ConstantTimeByteEq(b, byte(padNum))
+	}
+	if good != 1 {
+		return nil, ErrInvalidPadding
+	}
+	return plainText[:length-padNum], nil
+}
+
 // UnpaddingLastGroup 去掉明文后面的填充数据
+// 填充非法时原样返回且不报错,解密请使用 UnpadPKCS7
 func UnpaddingLastGroup(plainText []byte) []byte {
 	//1.拿到切片中的最后一个字节
 	length := len(plainText)
@@ -172,3 +322,45 @@ func UnpaddingLastGroup(plainText []byte) []byte {
 	}
 	return plainText[:length-number]
 }
+
+// sm4Pool 同一密钥的SM4实例池
+// gmsm 的 Sm4Cipher 加解密时复用内部缓冲区,不能在多个goroutine间共享,
+// 这里每次加解密调用或每个流取出一个独立实例,密钥扩展只在创建实例时进行
+type sm4Pool[T any] struct {
+	pool sync.Pool
+}
+
+// newSM4Pool 创建实例池,先创建一个实例以检查密钥
+// newInstance 使用同一密钥创建实例
+func newSM4Pool[T any](newInstance func() (T, error)) (*sm4Pool[T], error) {
+	first, err := newInstance()
+	if err != nil {
+		return nil, err
+	}
+	p := &sm4Pool[T]{}
+	p.pool.New = func() any {
+		v, _ := newInstance()
+		return v
+	}
+	p.pool.Put(first)
+	return p, nil
+}
+
+func (p *sm4Pool[T]) get() T  { return p.pool.Get().(T) }
+func (p *sm4Pool[T]) put(v T) { p.pool.Put(v) }
+
+// newSM4Blocks 创建SM4分组密码实例池
+// key 16字节密钥
+func newSM4Blocks(key []byte) (*sm4Pool[cipher.Block], error) {
+	if len(key) != sm4BlockSize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrSM4InvalidKey, sm4BlockSize, len(key))
+	}
+	key = bytes.Clone(key)
+	return newSM4Pool(func() (cipher.Block, error) {
+		block, err := sm4.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSM4InvalidKey, err)
+		}
+		return block, nil
+	})
+}
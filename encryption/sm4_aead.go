@@ -0,0 +1,294 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// AEADMode SM4认证加密模式
+type AEADMode int
+
+const (
+	// AEADGCM SM4-GCM,随机数12字节,认证标签16字节
+	AEADGCM AEADMode = iota
+	// AEADCCM SM4-CCM,随机数12字节,认证标签16字节
+	AEADCCM
+)
+
+const (
+	sm4AEADNonceSize = 12
+	sm4AEADTagSize   = 16
+)
+
+var (
+	// ErrSM4Authentication 认证失败: 密钥、随机数或附加数据不一致,或密文被篡改
+	ErrSM4Authentication = errors.New("sm4: message authentication failed")
+	// ErrInvalidNonce 随机数长度错误
+	ErrInvalidNonce = errors.New("sm4: invalid nonce")
+)
+
+// String 返回模式名称
+func (m AEADMode) String() string {
+	switch m {
+	case AEADGCM:
+		return "SM4-GCM"
+	case AEADCCM:
+		return "SM4-CCM"
+	default:
+		return fmt.Sprintf("AEADMode(%d)", int(m))
+	}
+}
+
+// SM4AEAD SM4认证加密(GCM/CCM),密文被篡改时解密返回 ErrSM4Authentication
+// 构造后只读,可在多个goroutine间共享
+//
+// Encrypt 系列每次生成随机数并置于密文之前,输出为 随机数||密文||认证标签;
+// 需要自行管理随机数时使用 Seal/Open
+type SM4AEAD struct {
+	mode  AEADMode
+	aeads *sm4Pool[cipher.AEAD]
+}
+
+// NewSM4AEAD 新建SM4认证加密
+// key 16字节密钥
+// mode 认证加密模式
+func NewSM4AEAD(key []byte, mode AEADMode) (*SM4AEAD, error) {
+	if mode != AEADGCM && mode != AEADCCM {
+		return nil, fmt.Errorf("sm4: unsupported AEAD mode %v", mode)
+	}
+	blocks, err := newSM4Blocks(key)
+	if err != nil {
+		return nil, err
+	}
+	// 每个实例持有独立的分组密码,实例本身同样不能并发使用
+	aeads, err := newSM4Pool(func() (cipher.AEAD, error) {
+		block := blocks.get()
+		if mode == AEADCCM {
+			return newCCM(block, sm4AEADNonceSize, sm4AEADTagSize)
+		}
+		return cipher.NewGCM(block)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SM4AEAD{mode: mode, aeads: aeads}, nil
+}
+
+// AEADFromHex 使用16进制密钥新建SM4认证加密
+func AEADFromHex(key string, mode AEADMode) (*SM4AEAD, error) {
+	keyByts, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewSM4AEAD(keyByts, mode)
+}
+
+// AEADFromBase64 使用Base64密钥新建SM4认证加密
+func AEADFromBase64(key string, mode AEADMode) (*SM4AEAD, error) {
+	keyByts, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewSM4AEAD(keyByts, mode)
+}
+
+// Mode 认证加密模式
+func (enc *SM4AEAD) Mode() AEADMode {
+	return enc.mode
+}
+
+// NonceSize 随机数字节长度
+func (enc *SM4AEAD) NonceSize() int {
+	return sm4AEADNonceSize
+}
+
+// Overhead 认证标签字节长度
+func (enc *SM4AEAD) Overhead() int {
+	return sm4AEADTagSize
+}
+
+// Seal 使用指定随机数加密,返回 密文||认证标签
+// 同一密钥下随机数绝不能重复使用
+// nonce 随机数,长度为 NonceSize
+// plaintext 待加密明文
+// additionalData 附加认证数据,只认证不加密,可为nil
+func (enc *SM4AEAD) Seal(nonce, plaintext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != sm4AEADNonceSize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidNonce, sm4AEADNonceSize, len(nonce))
+	}
+	aead := enc.aeads.get()
+	defer enc.aeads.put(aead)
+	return aead.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+// Open 使用指定随机数解密并认证 Seal 的输出
+// nonce 加密时使用的随机数
+// ciphertext 密文||认证标签
+// additionalData 加密时使用的附加认证数据
+func (enc *SM4AEAD) Open(nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != sm4AEADNonceSize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidNonce, sm4AEADNonceSize, len(nonce))
+	}
+	aead := enc.aeads.get()
+	defer enc.aeads.put(aead)
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrSM4Authentication
+	}
+	return plaintext, nil
+}
+
+// Encrypt 使用随机数加密,返回 随机数||密文||认证标签
+// plaintext 待加密明文字符串
+// additionalData 附加认证数据,可为nil
+func (enc *SM4AEAD) Encrypt(plaintext string, additionalData []byte) ([]byte, error) {
+	return enc.EncryptBytes([]byte(plaintext), additionalData)
+}
+
+// EncryptBytes 使用随机数加密二进制数据,返回 随机数||密文||认证标签
+// plaintext 待加密明文
+// additionalData 附加认证数据,可为nil
+func (enc *SM4AEAD) EncryptBytes(plaintext, additionalData []byte) ([]byte, error) {
+	out := make([]byte, sm4AEADNonceSize, sm4AEADNonceSize+len(plaintext)+sm4AEADTagSize)
+	if _, err := rand.Read(out); err != nil {
+		return nil, err
+	}
+	aead := enc.aeads.get()
+	defer enc.aeads.put(aead)
+	return aead.Seal(out, out, plaintext, additionalData), nil
+}
+
+// EncryptEncoded 加密并按指定编码返回密文字符串
+// plaintext 待加密明文
+// additionalData 附加认证数据,可为nil
+// encoding 密文编码,见 Encoding
+func (enc *SM4AEAD) EncryptEncoded(plaintext, additionalData []byte, encoding Encoding) (string, error) {
+	ciphertext, err := enc.EncryptBytes(plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return encoding.Encode(ciphertext)
+}
+
+// Encrypt2Hex 加密并返回16进制字符串
+// plaintext 待加密明文字符串
+// additionalData 附加认证数据,可为nil
+func (enc *SM4AEAD) Encrypt2Hex(plaintext string, additionalData []byte) (string, error) {
+	encryptedByts, err := enc.Encrypt(plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(encryptedByts), nil
+}
+
+// Encrypt2Base64 加密并返回Base64字符串
+// plaintext 待加密明文字符串
+// additionalData 附加认证数据,可为nil
+func (enc *SM4AEAD) Encrypt2Base64(plaintext string, additionalData []byte) (string, error) {
+	encryptedByts, err := enc.Encrypt(plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encryptedByts), nil
+}
+
+// EncryptObject 加密JSON对象
+// 返回原始密文字节;需要与 DecryptObject 对称时使用 EncryptObjectEncoded
+// obj 待加密对象
+// additionalData 附加认证数据,可为nil
+func (enc *SM4AEAD) EncryptObject(obj any, additionalData []byte) ([]byte, error) {
+	marshal, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return enc.EncryptBytes(marshal, additionalData)
+}
+
+// EncryptObjectEncoded 序列化并加密对象,按指定编码返回密文字符串,与 DecryptObjectEncoded 对称
+// obj 待加密对象
+// additionalData 附加认证数据,可为nil
+// c 序列化方式,nil 为 JSONCodec
+// encoding 密文编码,见 Encoding
+func (enc *SM4AEAD) EncryptObjectEncoded(obj any, additionalData []byte, c Codec, encoding Encoding) (string, error) {
+	marshal, err := codecOrDefault(c).Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return enc.EncryptEncoded(marshal, additionalData, encoding)
+}
+
+// Decrypt 解密并认证 Encrypt 的输出
+// ciphertext 随机数||密文||认证标签
+// additionalData 加密时使用的附加认证数据
+func (enc *SM4AEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < sm4AEADNonceSize+sm4AEADTagSize {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrSM4Authentication)
+	}
+	return enc.Open(ciphertext[:sm4AEADNonceSize], ciphertext[sm4AEADNonceSize:], additionalData)
+}
+
+// DecryptEncoded 解密指定编码的密文字符串
+// ciphertext 待解密密文字符串
+// additionalData 加密时使用的附加认证数据
+// encoding 密文编码,见 Encoding
+func (enc *SM4AEAD) DecryptEncoded(ciphertext string, additionalData []byte, encoding Encoding) ([]byte, error) {
+	decodeByes, err := encoding.Decode(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return enc.Decrypt(decodeByes, additionalData)
+}
+
+// DecryptHex 解密16进制密文字符串
+// ciphertext 待解密密文字符串
+// additionalData 加密时使用的附加认证数据
+func (enc *SM4AEAD) DecryptHex(ciphertext string, additionalData []byte) ([]byte, error) {
+	decodeByes, err := hex.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return enc.Decrypt(decodeByes, additionalData)
+}
+
+// DecryptBase64 解密Base64密文字符串
+// ciphertext 待解密密文字符串
+// additionalData 加密时使用的附加认证数据
+func (enc *SM4AEAD) DecryptBase64(ciphertext string, additionalData []byte) ([]byte, error) {
+	decodeByes, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return enc.Decrypt(decodeByes, additionalData)
+}
+
+// DecryptObject 解密16进制密文字符串并解码JSON对象
+// 其他编码或序列化格式使用 DecryptObjectEncoded
+// ciphertext 待解密密文字符串
+// additionalData 加密时使用的附加认证数据
+// obj 解码对象
+func (enc *SM4AEAD) DecryptObject(ciphertext string, additionalData []byte, obj any) error {
+	decrypt, err := enc.DecryptHex(ciphertext, additionalData)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(decrypt, obj)
+}
+
+// DecryptObjectEncoded 解密 EncryptObjectEncoded 的输出并反序列化到对象
+// ciphertext 待解密密文字符串
+// additionalData 加密时使用的附加认证数据
+// c 序列化方式,须与加密时一致,nil 为 JSONCodec
+// encoding 密文编码,须与加密时一致
+// obj 解码对象
+func (enc *SM4AEAD) DecryptObjectEncoded(ciphertext string, additionalData []byte, c Codec, encoding Encoding, obj any) error {
+	decrypt, err := enc.DecryptEncoded(ciphertext, additionalData, encoding)
+	if err != nil {
+		return err
+	}
+	return codecOrDefault(c).Unmarshal(decrypt, obj)
+}
@@ -0,0 +1,211 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	// StreamChunkSize 分块认证加密流的明文分块大小
+	StreamChunkSize = 64 * 1024
+
+	// streamNoncePrefixSize 随机数前缀长度;随机数 = 前缀(7) || 分块序号(4) || 末块标记(1)
+	streamNoncePrefixSize = 7
+)
+
+// NewEncryptWriter 创建分块认证加密流,内存占用与数据大小无关
+// 输出为 7字节随机数前缀 || 分块1 || 分块2 ...,每个分块为 StreamChunkSize 字节明文加密后的密文||认证标签,
+// 最后一个分块(可能为空)带末块标记,解密时可发现截断、重排和删除分块。
+// 必须调用 Close 写出最后一个分块;Close 不会关闭w
+// w 密文输出
+// additionalData 附加认证数据,对每个分块生效,可为nil
+func (enc *SM4AEAD) NewEncryptWriter(w io.Writer, additionalData []byte) (io.WriteCloser, error) {
+	prefix := make([]byte, streamNoncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return nil, err
+	}
+	return newChunkWriter(enc.aeads.get(), w, prefix, StreamChunkSize, additionalData), nil
+}
+
+// NewDecryptReader 创建分块认证解密流,读取 NewEncryptWriter 的输出
+// 每个分块认证通过后才返回其明文;分块被篡改、截断或缺少末块时返回 ErrSM4Authentication。
+// 注意出错前已返回的明文属于已认证的分块,但整个流直到读到EOF才算完整
+// r 密文输入
+// additionalData 加密时使用的附加认证数据
+func (enc *SM4AEAD) NewDecryptReader(r io.Reader, additionalData []byte) (io.Reader, error) {
+	prefix := make([]byte, streamNoncePrefixSize)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("%w: missing nonce prefix", ErrSM4Authentication)
+		}
+		return nil, err
+	}
+	return newChunkReader(enc.aeads.get(), r, prefix, StreamChunkSize, additionalData), nil
+}
+
+// chunkNonce 分块随机数: 前缀 || 序号 || 末块标记
+func chunkNonce(nonce []byte, seq uint32, final bool) []byte {
+	binary.BigEndian.PutUint32(nonce[streamNoncePrefixSize:], seq)
+	nonce[len(nonce)-1] = 0
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// chunkWriter 分块认证加密
+// 缓冲区满时不能立即写出,需等到有更多数据才能确定它不是最后一个分块
+type chunkWriter struct {
+	aead      cipher.AEAD
+	w         io.Writer
+	nonce     []byte
+	seq       uint32
+	chunkSize int
+	ad        []byte
+	buf       []byte
+	err       error
+}
+
+// newChunkWriter 创建分块认证加密,不写入前缀
+func newChunkWriter(aead cipher.AEAD, w io.Writer, prefix []byte, chunkSize int, additionalData []byte) *chunkWriter {
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, prefix)
+	return &chunkWriter{
+		aead:      aead,
+		w:         w,
+		nonce:     nonce,
+		chunkSize: chunkSize,
+		ad:        additionalData,
+		buf:       make([]byte, 0, chunkSize+aead.Overhead()),
+	}
+}
+
+func (cw *chunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for cw.err == nil && len(p) > 0 {
+		if len(cw.buf) == cw.chunkSize {
+			cw.seal(false)
+			continue
+		}
+		n := min(len(p), cw.chunkSize-len(cw.buf))
+		cw.buf = append(cw.buf, p[:n]...)
+		written += n
+		p = p[n:]
+	}
+	return written, cw.err
+}
+
+// seal 加密并写出缓冲区中的分块
+func (cw *chunkWriter) seal(final bool) {
+	if cw.seq == math.MaxUint32 && !final {
+		cw.err = fmt.Errorf("sm4: stream exceeds %d chunks", uint64(math.MaxUint32))
+		return
+	}
+	sealed := cw.aead.Seal(cw.buf[:0], chunkNonce(cw.nonce, cw.seq, final), cw.buf, cw.ad)
+	if _, err := cw.w.Write(sealed); err != nil {
+		cw.err = err
+		return
+	}
+	cw.seq++
+	cw.buf = cw.buf[:0]
+}
+
+// Close 写出最后一个分块
+func (cw *chunkWriter) Close() error {
+	if cw.err == errWriterClosed {
+		return nil
+	}
+	if cw.err == nil {
+		cw.seal(true)
+	}
+	err := cw.err
+	cw.err = errWriterClosed
+	return err
+}
+
+// chunkReader 分块认证解密
+// 每次多读取一个字节,读到EOF的分块即为最后一个分块
+type chunkReader struct {
+	aead      cipher.AEAD
+	r         io.Reader
+	nonce     []byte
+	seq       uint32
+	chunkSize int
+	ad        []byte
+	buf       []byte
+	out       []byte
+	err       error
+}
+
+// newChunkReader 创建分块认证解密,前缀需已从r读出
+func newChunkReader(aead cipher.AEAD, r io.Reader, prefix []byte, chunkSize int, additionalData []byte) *chunkReader {
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, prefix)
+	return &chunkReader{
+		aead:      aead,
+		r:         r,
+		nonce:     nonce,
+		chunkSize: chunkSize,
+		ad:        additionalData,
+		buf:       make([]byte, 0, chunkSize+aead.Overhead()+1),
+	}
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	for len(cr.out) == 0 {
+		if cr.err != nil {
+			return 0, cr.err
+		}
+		cr.open()
+	}
+	n := copy(p, cr.out)
+	cr.out = cr.out[n:]
+	return n, nil
+}
+
+// open 读取并解密下一个分块
+func (cr *chunkReader) open() {
+	sealedSize := cr.chunkSize + cr.aead.Overhead()
+	if len(cr.buf) > sealedSize {
+		// 上次多读的一个字节属于本分块
+		cr.buf[0] = cr.buf[sealedSize]
+		cr.buf = cr.buf[:1]
+	}
+	n, err := io.ReadFull(cr.r, cr.buf[len(cr.buf):sealedSize+1])
+	cr.buf = cr.buf[:len(cr.buf)+n]
+
+	final := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		final = true
+	case err != nil:
+		cr.err = err
+		return
+	}
+	chunk := cr.buf
+	if !final {
+		chunk = cr.buf[:sealedSize]
+	}
+	if !final && cr.seq == math.MaxUint32 {
+		// 序号用尽后只可能是最后一个分块
+		cr.err = ErrSM4Authentication
+		return
+	}
+	plaintext, err := cr.aead.Open(chunk[:0], chunkNonce(cr.nonce, cr.seq, final), chunk, cr.ad)
+	if err != nil {
+		cr.err = ErrSM4Authentication
+		return
+	}
+	cr.seq++
+	cr.out = plaintext
+	if final {
+		cr.err = io.EOF
+	}
+}
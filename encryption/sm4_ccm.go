@@ -0,0 +1,155 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ccm CCM 认证加密模式(NIST SP 800-38C / RFC 3610),仅支持128位分组密码
+// 标准库与 gmsm 均未提供 CCM,这里按 cipher.AEAD 接口实现
+type ccm struct {
+	block     cipher.Block
+	nonceSize int
+	tagSize   int
+}
+
+var errCCMOpen = errors.New("ccm: message authentication failed")
+
+// newCCM 创建CCM模式
+// nonceSize 随机数字节长度,7~13
+// tagSize 认证标签字节长度,4~16之间的偶数
+func newCCM(block cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if block.BlockSize() != sm4BlockSize {
+		return nil, errors.New("ccm: block size must be 16 bytes")
+	}
+	if nonceSize < 7 || nonceSize > 13 {
+		return nil, errors.New("ccm: invalid nonce size")
+	}
+	if tagSize < 4 || tagSize > 16 || tagSize%2 != 0 {
+		return nil, errors.New("ccm: invalid tag size")
+	}
+	return &ccm{block: block, nonceSize: nonceSize, tagSize: tagSize}, nil
+}
+
+func (c *ccm) NonceSize() int { return c.nonceSize }
+
+func (c *ccm) Overhead() int { return c.tagSize }
+
+// maxLength 明文最大长度,由长度字段的字节数 L=15-nonceSize 决定
+func (c *ccm) maxLength() uint64 {
+	l := 15 - c.nonceSize
+	if l >= 8 {
+		return math.MaxInt
+	}
+	return 1<<(8*uint(l)) - 1
+}
+
+// counter 计数块 A_i = flags || N || i
+func (c *ccm) counter(nonce []byte, i uint64) []byte {
+	ctr := make([]byte, sm4BlockSize)
+	ctr[0] = byte(14 - c.nonceSize)
+	copy(ctr[1:], nonce)
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], i)
+	copy(ctr[1+c.nonceSize:], n[8-(15-c.nonceSize):])
+	return ctr
+}
+
+// mac 对 B0 || 附加数据 || 明文 计算 CBC-MAC
+func (c *ccm) mac(nonce, plaintext, additionalData []byte) []byte {
+	l := 15 - c.nonceSize
+	var b0 [sm4BlockSize]byte
+	b0[0] = byte((c.tagSize-2)/2<<3 | (l - 1))
+	if len(additionalData) > 0 {
+		b0[0] |= 0x40
+	}
+	copy(b0[1:], nonce)
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(plaintext)))
+	copy(b0[1+c.nonceSize:], n[8-l:])
+
+	tag := make([]byte, sm4BlockSize)
+	c.block.Encrypt(tag, b0[:])
+
+	if len(additionalData) > 0 {
+		var header []byte
+		switch size := uint64(len(additionalData)); {
+		case size < 0xff00:
+			header = binary.BigEndian.AppendUint16(nil, uint16(size))
+		case size <= math.MaxUint32:
+			header = binary.BigEndian.AppendUint32([]byte{0xff, 0xfe}, uint32(size))
+		default:
+			header = binary.BigEndian.AppendUint64([]byte{0xff, 0xff}, size)
+		}
+		c.cbcMAC(tag, append(header, additionalData...))
+	}
+	c.cbcMAC(tag, plaintext)
+	return tag
+}
+
+// cbcMAC 将数据补零至分组长度后继续CBC-MAC链
+func (c *ccm) cbcMAC(tag, data []byte) {
+	for len(data) > 0 {
+		n := subtle.XORBytes(tag, tag, data)
+		c.block.Encrypt(tag, tag)
+		data = data[n:]
+	}
+}
+
+func (c *ccm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != c.nonceSize {
+		panic("ccm: incorrect nonce length given to CCM")
+	}
+	if uint64(len(plaintext)) > c.maxLength() {
+		panic("ccm: message too large for CCM")
+	}
+	tag := c.mac(nonce, plaintext, additionalData)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+c.tagSize)
+	cipher.NewCTR(c.block, c.counter(nonce, 1)).XORKeyStream(out, plaintext)
+
+	s0 := make([]byte, sm4BlockSize)
+	c.block.Encrypt(s0, c.counter(nonce, 0))
+	subtle.XORBytes(out[len(plaintext):], tag[:c.tagSize], s0)
+	return ret
+}
+
+func (c *ccm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != c.nonceSize {
+		panic("ccm: incorrect nonce length given to CCM")
+	}
+	if len(ciphertext) < c.tagSize || uint64(len(ciphertext)-c.tagSize) > c.maxLength() {
+		return nil, errCCMOpen
+	}
+	tagged := ciphertext[len(ciphertext)-c.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-c.tagSize]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	cipher.NewCTR(c.block, c.counter(nonce, 1)).XORKeyStream(out, ciphertext)
+
+	s0 := make([]byte, sm4BlockSize)
+	c.block.Encrypt(s0, c.counter(nonce, 0))
+	expected := c.mac(nonce, out, additionalData)
+	subtle.XORBytes(expected, expected[:c.tagSize], s0)
+
+	if subtle.ConstantTimeCompare(expected[:c.tagSize], tagged) != 1 {
+		clear(out)
+		return nil, errCCMOpen
+	}
+	return ret, nil
+}
+
+// sliceForAppend 扩展in以容纳n个字节,返回扩展后的切片及新增部分
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,204 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SM4文件加密格式(版本1),所有整数为大端序:
+//
+//	magic        4字节  "SM4F"
+//	version      1字节  0x01
+//	algorithm    1字节  0x01 = SM4-GCM
+//	chunkSize    4字节  明文分块字节数
+//	keyIDLen     1字节
+//	keyID        keyIDLen字节
+//	noncePrefix  7字节
+//	chunks       每个分块为 chunkSize 字节明文的 密文||16字节认证标签,最后一个分块可更短(可为空)
+//
+// 分块随机数为 noncePrefix || 分块序号(4字节,从0开始) || 末块标记(1字节),
+// 整个文件头作为每个分块的附加认证数据,篡改文件头、截断或重排分块都会导致认证失败
+const (
+	// FileVersion 当前文件格式版本
+	FileVersion = 1
+
+	fileMagic        = "SM4F"
+	fileFixedHeader  = len(fileMagic) + 1 + 1 + 4 + 1
+	maxFileKeyIDSize = 255
+	maxFileChunkSize = 16 * 1024 * 1024
+)
+
+// fileAlgorithms 文件头中的算法编号
+var fileAlgorithms = map[byte]AEADMode{
+	0x01: AEADGCM,
+}
+
+// ErrInvalidFile 文件头格式错误或版本、算法不受支持
+var ErrInvalidFile = errors.New("sm4 file: invalid file")
+
+// FileOptions 文件加密选项
+type FileOptions struct {
+	// KeyID 密钥标识,明文写入文件头,解密时据此选择密钥,最长255字节
+	KeyID string
+	// ChunkSize 明文分块字节数,默认 StreamChunkSize,最大16MiB
+	ChunkSize int
+}
+
+// FileKeyFunc 根据文件头中的密钥标识返回16字节SM4密钥
+type FileKeyFunc func(keyID string) ([]byte, error)
+
+// FileHeader 文件头
+type FileHeader struct {
+	Version     int
+	Algorithm   AEADMode
+	ChunkSize   int
+	KeyID       string
+	NoncePrefix []byte
+
+	// raw 原始文件头,作为分块的附加认证数据
+	raw []byte
+}
+
+// NewFileWriter 创建文件加密流,先写入文件头,写入的明文按分块加密后写入w
+// 必须调用 Close 写出最后一个分块;Close 不会关闭w
+// w 密文输出
+// key 16字节SM4密钥
+// opts 加密选项,可为nil
+func NewFileWriter(w io.Writer, key []byte, opts *FileOptions) (io.WriteCloser, error) {
+	if opts == nil {
+		opts = &FileOptions{}
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = StreamChunkSize
+	}
+	if chunkSize < 0 || chunkSize > maxFileChunkSize {
+		return nil, fmt.Errorf("%w: chunk size %d out of range", ErrInvalidFile, chunkSize)
+	}
+	if len(opts.KeyID) > maxFileKeyIDSize {
+		return nil, fmt.Errorf("%w: key ID longer than %d bytes", ErrInvalidFile, maxFileKeyIDSize)
+	}
+	aead, err := NewSM4AEAD(key, AEADGCM)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, streamNoncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, fileFixedHeader+len(opts.KeyID)+streamNoncePrefixSize)
+	header = append(header, fileMagic...)
+	header = append(header, FileVersion, 0x01)
+	header = binary.BigEndian.AppendUint32(header, uint32(chunkSize))
+	header = append(header, byte(len(opts.KeyID)))
+	header = append(header, opts.KeyID...)
+	header = append(header, prefix...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return newChunkWriter(aead.aeads.get(), w, prefix, chunkSize, header), nil
+}
+
+// ReadFileHeader 读取并解析文件头,r随后可传给 (*FileHeader).NewReader
+// 文件头此时尚未认证,其内容仅可用于选择密钥
+func ReadFileHeader(r io.Reader) (*FileHeader, error) {
+	fixed := make([]byte, fileFixedHeader)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, fileReadError(err)
+	}
+	if string(fixed[:len(fileMagic)]) != fileMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidFile)
+	}
+	h := &FileHeader{Version: int(fixed[4])}
+	if h.Version != FileVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidFile, h.Version)
+	}
+	mode, ok := fileAlgorithms[fixed[5]]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm 0x%02x", ErrInvalidFile, fixed[5])
+	}
+	h.Algorithm = mode
+	h.ChunkSize = int(binary.BigEndian.Uint32(fixed[6:10]))
+	if h.ChunkSize <= 0 || h.ChunkSize > maxFileChunkSize {
+		return nil, fmt.Errorf("%w: chunk size %d out of range", ErrInvalidFile, h.ChunkSize)
+	}
+
+	rest := make([]byte, int(fixed[10])+streamNoncePrefixSize)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, fileReadError(err)
+	}
+	h.KeyID = string(rest[:fixed[10]])
+	h.NoncePrefix = rest[fixed[10]:]
+	h.raw = append(fixed, rest...)
+	return h, nil
+}
+
+// NewReader 创建文件解密流,r需位于文件头之后
+// 分块被篡改、截断或重排,或密钥错误时返回 ErrSM4Authentication
+// r 密文输入
+// key 16字节SM4密钥
+func (h *FileHeader) NewReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := NewSM4AEAD(key, h.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return newChunkReader(aead.aeads.get(), r, h.NoncePrefix, h.ChunkSize, h.raw), nil
+}
+
+// NewFileReader 读取文件头,按其中的密钥标识获取密钥,返回文件解密流
+// r 密文输入
+// keys 密钥查找函数
+func NewFileReader(r io.Reader, keys FileKeyFunc) (io.Reader, error) {
+	h, err := ReadFileHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keys(h.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return h.NewReader(r, key)
+}
+
+// EncodeFile 将数据加密为文件格式
+// plaintext 待加密数据
+// key 16字节SM4密钥
+// opts 加密选项,可为nil
+func EncodeFile(plaintext, key []byte, opts *FileOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewFileWriter(&buf, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeFile 解密文件格式的数据
+// data 文件内容
+// keys 密钥查找函数
+func DecodeFile(data []byte, keys FileKeyFunc) ([]byte, error) {
+	r, err := NewFileReader(bytes.NewReader(data), keys)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// fileReadError 文件头不完整时返回 ErrInvalidFile
+func fileReadError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: truncated header", ErrInvalidFile)
+	}
+	return err
+}
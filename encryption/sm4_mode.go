@@ -0,0 +1,159 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+)
+
+// SM4Mode SM4分组密码工作模式
+type SM4Mode int
+
+const (
+	// ModeCBC 密码分组链接模式,PKCS#7填充,默认模式
+	ModeCBC SM4Mode = iota
+	// ModeECB 电子密码本模式,PKCS#7填充,不使用IV;相同明文分组得到相同密文分组,仅用于兼容
+	ModeECB
+	// ModeCTR 计数器模式,不填充,密文与明文等长
+	ModeCTR
+	// ModeCFB 128位密文反馈模式,不填充
+	ModeCFB
+	// ModeOFB 输出反馈模式,不填充
+	ModeOFB
+)
+
+// String 返回模式名称
+func (m SM4Mode) String() string {
+	switch m {
+	case ModeCBC:
+		return "SM4-CBC"
+	case ModeECB:
+		return "SM4-ECB"
+	case ModeCTR:
+		return "SM4-CTR"
+	case ModeCFB:
+		return "SM4-CFB"
+	case ModeOFB:
+		return "SM4-OFB"
+	default:
+		return fmt.Sprintf("SM4Mode(%d)", int(m))
+	}
+}
+
+// padded 分组模式需要PKCS#7填充,流模式不需要
+func (m SM4Mode) padded() bool {
+	return m == ModeCBC || m == ModeECB
+}
+
+// SM4Option SM4构造选项
+type SM4Option func(*SM4)
+
+// WithMode 指定工作模式,默认 ModeCBC
+func WithMode(mode SM4Mode) SM4Option {
+	return func(enc *SM4) {
+		enc.mode = mode
+	}
+}
+
+// WithRandomIV 每次加密生成随机IV并置于密文之前(IV||密文),解密时从密文中读取
+// 相同明文每次得到不同密文;构造时传入的IV被忽略,可为nil。不适用于ECB模式
+func WithRandomIV() SM4Option {
+	return func(enc *SM4) {
+		enc.randomIV = true
+	}
+}
+
+// WithFixedIV 流模式下明确使用构造时传入的固定IV,用于兼容已有密文或对接约定IV的外部系统
+// 流模式(CTR/CFB/OFB)须指定 WithRandomIV 或本选项,否则 NewSM4 返回 ErrSM4InvalidIV;
+// 固定IV下同一密钥的每个IV只能加密一次,否则密钥流复用会泄露明文。分组模式下无影响
+func WithFixedIV() SM4Option {
+	return func(enc *SM4) {
+		enc.fixedIV = true
+	}
+}
+
+// Mode 工作模式
+func (enc *SM4) Mode() SM4Mode {
+	return enc.mode
+}
+
+// applyOptions 应用构造选项并检查模式
+func (enc *SM4) applyOptions(opts []SM4Option) error {
+	for _, opt := range opts {
+		opt(enc)
+	}
+	if enc.mode < ModeCBC || enc.mode > ModeOFB {
+		return fmt.Errorf("sm4: unsupported mode %v", enc.mode)
+	}
+	if enc.randomIV && enc.mode == ModeECB {
+		return errors.New("sm4: random IV is not applicable to ECB mode")
+	}
+	if enc.randomIV && enc.fixedIV {
+		return errors.New("sm4: WithRandomIV and WithFixedIV are mutually exclusive")
+	}
+	if !enc.mode.padded() && !enc.randomIV && !enc.fixedIV {
+		return fmt.Errorf("%w: fixed IV with %v reuses the keystream; use WithRandomIV or WithFixedIV", ErrSM4InvalidIV, enc.mode)
+	}
+	return nil
+}
+
+// blockMode 返回CBC/ECB分组模式
+// block 从 enc.blocks 取出的实例,使用期间不能与其他goroutine共享
+func (enc *SM4) blockMode(block cipher.Block, iv []byte, decrypt bool) cipher.BlockMode {
+	switch {
+	case enc.mode == ModeECB && decrypt:
+		return ecbDecrypter{block}
+	case enc.mode == ModeECB:
+		return ecbEncrypter{block}
+	case decrypt:
+		return cipher.NewCBCDecrypter(block, iv)
+	default:
+		return cipher.NewCBCEncrypter(block, iv)
+	}
+}
+
+// stream 返回CTR/CFB/OFB流模式
+// block 从 blocks 取出的实例,使用期间不能与其他goroutine共享
+func (enc *SM4) stream(block cipher.Block, iv []byte, decrypt bool) cipher.Stream {
+	switch enc.mode {
+	case ModeCTR:
+		return cipher.NewCTR(block, iv)
+	case ModeOFB:
+		return cipher.NewOFB(block, iv)
+	default:
+		if decrypt {
+			return cipher.NewCFBDecrypter(block, iv)
+		}
+		return cipher.NewCFBEncrypter(block, iv)
+	}
+}
+
+// ecbEncrypter 标准库未提供ECB,按 cipher.BlockMode 逐块加密
+type ecbEncrypter struct{ b cipher.Block }
+
+func (x ecbEncrypter) BlockSize() int { return x.b.BlockSize() }
+
+func (x ecbEncrypter) CryptBlocks(dst, src []byte) {
+	bs := x.b.BlockSize()
+	if len(src)%bs != 0 {
+		panic("sm4: input not full blocks")
+	}
+	for i := 0; i < len(src); i += bs {
+		x.b.Encrypt(dst[i:i+bs], src[i:i+bs])
+	}
+}
+
+// ecbDecrypter 按 cipher.BlockMode 逐块解密
+type ecbDecrypter struct{ b cipher.Block }
+
+func (x ecbDecrypter) BlockSize() int { return x.b.BlockSize() }
+
+func (x ecbDecrypter) CryptBlocks(dst, src []byte) {
+	bs := x.b.BlockSize()
+	if len(src)%bs != 0 {
+		panic("sm4: input not full blocks")
+	}
+	for i := 0; i < len(src); i += bs {
+		x.b.Decrypt(dst[i:i+bs], src[i:i+bs])
+	}
+}
@@ -0,0 +1,190 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// sm4StreamBufferSize 流式加解密每次处理的字节数,为分组长度的整数倍
+const sm4StreamBufferSize = 32 * 1024
+
+var errWriterClosed = errors.New("sm4: write to closed writer")
+
+// NewEncryptWriter 创建流式加密,写入的明文加密后写入w,内存占用与数据大小无关
+// 输出与 Encrypt 一致: CBC/ECB 在 Close 时写入最后一个填充分组,流模式不填充;
+// 使用 WithRandomIV 时先写入随机IV。Close 不会关闭w
+// w 密文输出
+func (enc *SM4) NewEncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	iv := enc.iv
+	if enc.randomIV {
+		iv = make([]byte, sm4BlockSize)
+		if _, err := rand.Read(iv); err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(iv); err != nil {
+			return nil, err
+		}
+	}
+	// 流在整个生命周期内独占一个实例
+	block := enc.blocks.get()
+	if !enc.mode.padded() {
+		return &sm4StreamWriter{w: w, s: enc.stream(block, iv, false), buf: make([]byte, sm4StreamBufferSize)}, nil
+	}
+	return &sm4BlockWriter{w: w, mode: enc.blockMode(block, iv, false), buf: make([]byte, 0, sm4StreamBufferSize)}, nil
+}
+
+// NewDecryptReader 创建流式解密,从r读取密文并返回明文,内存占用与数据大小无关
+// CBC/ECB 模式读到EOF时校验填充,错误与 Decrypt 一致;使用 WithRandomIV 时先从r读取IV
+// r 密文输入
+func (enc *SM4) NewDecryptReader(r io.Reader) (io.Reader, error) {
+	iv := enc.iv
+	if enc.randomIV {
+		iv = make([]byte, sm4BlockSize)
+		if _, err := io.ReadFull(r, iv); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, fmt.Errorf("%w: missing IV prefix", ErrSM4InvalidCiphertext)
+			}
+			return nil, err
+		}
+	}
+	block := enc.blocks.get()
+	if !enc.mode.padded() {
+		return &cipher.StreamReader{S: enc.stream(block, iv, true), R: r}, nil
+	}
+	return &sm4BlockReader{r: r, mode: enc.blockMode(block, iv, true), buf: make([]byte, 0, sm4StreamBufferSize+sm4BlockSize)}, nil
+}
+
+// sm4StreamWriter CTR/CFB/OFB 流式加密
+type sm4StreamWriter struct {
+	w   io.Writer
+	s   cipher.Stream
+	buf []byte
+	err error
+}
+
+func (sw *sm4StreamWriter) Write(p []byte) (int, error) {
+	written := 0
+	for sw.err == nil && len(p) > 0 {
+		n := copy(sw.buf, p)
+		sw.s.XORKeyStream(sw.buf[:n], sw.buf[:n])
+		if _, err := sw.w.Write(sw.buf[:n]); err != nil {
+			sw.err = err
+			break
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, sw.err
+}
+
+func (sw *sm4StreamWriter) Close() error {
+	if sw.err == errWriterClosed {
+		return nil
+	}
+	err := sw.err
+	sw.err = errWriterClosed
+	return err
+}
+
+// sm4BlockWriter CBC/ECB 流式加密,不足一个分组的数据留到下次写入或 Close
+type sm4BlockWriter struct {
+	w    io.Writer
+	mode cipher.BlockMode
+	buf  []byte
+	err  error
+}
+
+func (bw *sm4BlockWriter) Write(p []byte) (int, error) {
+	written := 0
+	for bw.err == nil && len(p) > 0 {
+		n := min(len(p), cap(bw.buf)-len(bw.buf))
+		bw.buf = append(bw.buf, p[:n]...)
+		written += n
+		p = p[n:]
+		if len(bw.buf) == cap(bw.buf) {
+			bw.flush(len(bw.buf))
+		}
+	}
+	return written, bw.err
+}
+
+// flush 加密并写出缓冲区前n个字节,n为分组长度的整数倍
+func (bw *sm4BlockWriter) flush(n int) {
+	bw.mode.CryptBlocks(bw.buf[:n], bw.buf[:n])
+	if _, err := bw.w.Write(bw.buf[:n]); err != nil {
+		bw.err = err
+	}
+	bw.buf = bw.buf[:copy(bw.buf, bw.buf[n:])]
+}
+
+// Close 填充并写出最后一个分组
+func (bw *sm4BlockWriter) Close() error {
+	if bw.err == errWriterClosed {
+		return nil
+	}
+	if bw.err == nil {
+		bw.buf = PaddingLastGroup(bw.buf, sm4BlockSize)
+		bw.flush(len(bw.buf))
+	}
+	err := bw.err
+	bw.err = errWriterClosed
+	return err
+}
+
+// sm4BlockReader CBC/ECB 流式解密
+// 始终保留最后一个完整分组,读到EOF后才能确定它是否为填充分组
+type sm4BlockReader struct {
+	r    io.Reader
+	mode cipher.BlockMode
+	// buf[:done] 已解密,buf[done:] 待解密密文
+	buf  []byte
+	done int
+	out  []byte
+	err  error
+}
+
+func (br *sm4BlockReader) Read(p []byte) (int, error) {
+	for len(br.out) == 0 {
+		if br.err != nil {
+			return 0, br.err
+		}
+		br.fill()
+	}
+	n := copy(p, br.out)
+	br.out = br.out[n:]
+	return n, nil
+}
+
+func (br *sm4BlockReader) fill() {
+	br.buf = br.buf[:copy(br.buf, br.buf[br.done:])]
+	br.done = 0
+	n, err := br.r.Read(br.buf[len(br.buf):cap(br.buf)])
+	br.buf = br.buf[:len(br.buf)+n]
+
+	switch {
+	case err == io.EOF:
+		if len(br.buf) == 0 || len(br.buf)%sm4BlockSize != 0 {
+			br.err = fmt.Errorf("%w: length is not a multiple of the block size", ErrSM4InvalidCiphertext)
+			return
+		}
+		br.mode.CryptBlocks(br.buf, br.buf)
+		br.out, br.err = UnpadPKCS7(br.buf, sm4BlockSize)
+		if br.err == nil {
+			br.err = io.EOF
+		}
+	case err != nil:
+		br.err = err
+	default:
+		n := len(br.buf) - len(br.buf)%sm4BlockSize
+		if n == len(br.buf) {
+			n -= sm4BlockSize
+		}
+		if n > 0 {
+			br.mode.CryptBlocks(br.buf[:n], br.buf[:n])
+			br.out, br.done = br.buf[:n], n
+		}
+	}
+}
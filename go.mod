@@ -5,7 +5,9 @@ go 1.25
 require (
 	github.com/gin-gonic/gin v1.10.1
 	github.com/tjfoc/gmsm v1.4.1
+	github.com/ugorji/go/codec v1.3.0
 	go.etcd.io/etcd/api/v3 v3.6.4
+	google.golang.org/protobuf v1.36.8
 )
 
 require (
@@ -27,12 +29,10 @@ require (
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
-	github.com/ugorji/go/codec v1.3.0 // indirect
 	golang.org/x/arch v0.20.0 // indirect
 	golang.org/x/crypto v0.41.0 // indirect
 	golang.org/x/net v0.43.0 // indirect
 	golang.org/x/sys v0.35.0 // indirect
 	golang.org/x/text v0.28.0 // indirect
-	google.golang.org/protobuf v1.36.8 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
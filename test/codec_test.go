@@ -0,0 +1,162 @@
+package test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"xyz/test/helloworld/encryption"
+)
+
+type codecOrder struct {
+	ID     string   `json:"id"`
+	Amount int64    `json:"amount"`
+	Tags   []string `json:"tags"`
+	Paid   bool     `json:"paid"`
+}
+
+var allEncodings = []encryption.Encoding{encryption.EncodingHex, encryption.EncodingBase64, encryption.EncodingBase64URL, encryption.EncodingRaw}
+
+func TestCodecInterop(t *testing.T) {
+	// Fixed encodings of {"a":1} as produced by other languages' libraries
+	cases := []struct {
+		codec    encryption.Codec
+		expected string
+	}{
+		{encryption.JSONCodec, hex.EncodeToString([]byte(`{"a":1}`))},
+		{encryption.MsgpackCodec, "81a16101"},
+		{encryption.CBORCodec, "a1616101"},
+	}
+	for _, c := range cases {
+		data, err := c.codec.Marshal(map[string]int{"a": 1})
+		if err != nil {
+			t.Fatalf("%s: Marshal failed: %v", c.codec.Name(), err)
+		}
+		if got := hex.EncodeToString(data); got != c.expected {
+			t.Errorf("%s: Expected %s, got %s", c.codec.Name(), c.expected, got)
+		}
+		var decoded map[string]int
+		if err := c.codec.Unmarshal(data, &decoded); err != nil || decoded["a"] != 1 {
+			t.Errorf("%s: Unmarshal failed: %v %v", c.codec.Name(), err, decoded)
+		}
+	}
+}
+
+func TestSM4ObjectCodecs(t *testing.T) {
+	sm4, err := encryption.FromHex("0123456789ABCDEFFEDCBA9876543210", "000102030405060708090A0B0C0D0E0F")
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	order := codecOrder{ID: "订单-42", Amount: 1999, Tags: []string{"vip", "express"}, Paid: true}
+
+	for _, c := range []encryption.Codec{encryption.JSONCodec, encryption.GobCodec, encryption.MsgpackCodec, encryption.CBORCodec} {
+		for _, encoding := range allEncodings {
+			ciphertext, err := sm4.EncryptObjectEncoded(order, c, encoding)
+			if err != nil {
+				t.Fatalf("%s/%v: EncryptObjectEncoded failed: %v", c.Name(), encoding, err)
+			}
+			var decrypted codecOrder
+			if err := sm4.DecryptObjectEncoded(ciphertext, c, encoding, &decrypted); err != nil {
+				t.Fatalf("%s/%v: DecryptObjectEncoded failed: %v", c.Name(), encoding, err)
+			}
+			if decrypted.ID != order.ID || decrypted.Amount != order.Amount || decrypted.Paid != order.Paid ||
+				len(decrypted.Tags) != 2 || decrypted.Tags[1] != "express" {
+				t.Errorf("%s/%v: Decrypted object mismatch: %+v", c.Name(), encoding, decrypted)
+			}
+		}
+	}
+
+	// A nil codec with hex encoding is interchangeable with DecryptObject
+	ciphertext, err := sm4.EncryptObjectEncoded(order, nil, encryption.EncodingHex)
+	if err != nil {
+		t.Fatalf("EncryptObjectEncoded failed: %v", err)
+	}
+	var legacy codecOrder
+	if err := sm4.DecryptObject(ciphertext, &legacy); err != nil || legacy.ID != order.ID {
+		t.Errorf("DecryptObject of EncryptObjectEncoded output failed: %v", err)
+	}
+
+	// Decoding with a different codec than was used to encrypt fails
+	ciphertext, err = sm4.EncryptObjectEncoded(order, encryption.GobCodec, encryption.EncodingBase64)
+	if err != nil {
+		t.Fatalf("EncryptObjectEncoded failed: %v", err)
+	}
+	var wrong codecOrder
+	if err := sm4.DecryptObjectEncoded(ciphertext, encryption.JSONCodec, encryption.EncodingBase64, &wrong); err == nil {
+		t.Error("Expected error decoding gob data as JSON")
+	}
+}
+
+func TestObjectCodecsProtobuf(t *testing.T) {
+	ts := timestamppb.New(time.Date(2024, 7, 1, 8, 30, 0, 123, time.UTC))
+
+	aead, err := encryption.AEADFromHex(rfc8998Key, encryption.AEADGCM)
+	if err != nil {
+		t.Fatalf("AEADFromHex failed: %v", err)
+	}
+	aad := []byte("event-7")
+	ciphertext, err := aead.EncryptObjectEncoded(ts, aad, encryption.ProtobufCodec, encryption.EncodingBase64URL)
+	if err != nil {
+		t.Fatalf("EncryptObjectEncoded failed: %v", err)
+	}
+	decrypted := &timestamppb.Timestamp{}
+	if err := aead.DecryptObjectEncoded(ciphertext, aad, encryption.ProtobufCodec, encryption.EncodingBase64URL, decrypted); err != nil {
+		t.Fatalf("DecryptObjectEncoded failed: %v", err)
+	}
+	if !proto.Equal(decrypted, ts) {
+		t.Errorf("Decrypted message mismatch: %v", decrypted)
+	}
+	if err := aead.DecryptObjectEncoded(ciphertext, []byte("other"), encryption.ProtobufCodec, encryption.EncodingBase64URL, decrypted); !errors.Is(err, encryption.ErrSM4Authentication) {
+		t.Errorf("Expected ErrSM4Authentication for wrong AAD, got: %v", err)
+	}
+
+	publicKeyHex, privateKeyHex, err := generateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	sm2, err := encryption.NewSM2(publicKeyHex, privateKeyHex)
+	if err != nil {
+		t.Fatalf("Failed to create SM2 instance: %v", err)
+	}
+	for _, encoding := range allEncodings {
+		ciphertext, err := sm2.EncryptObjectEncoded(ts, encryption.CipherASN1, encryption.ProtobufCodec, encoding)
+		if err != nil {
+			t.Fatalf("%v: SM2 EncryptObjectEncoded failed: %v", encoding, err)
+		}
+		decrypted := &timestamppb.Timestamp{}
+		if err := sm2.DecryptObjectEncoded(ciphertext, encryption.CipherASN1, encryption.ProtobufCodec, encoding, decrypted); err != nil {
+			t.Fatalf("%v: SM2 DecryptObjectEncoded failed: %v", encoding, err)
+		}
+		if !proto.Equal(decrypted, ts) {
+			t.Errorf("%v: SM2 decrypted message mismatch: %v", encoding, decrypted)
+		}
+	}
+
+	// Plain structs are rejected instead of silently producing empty output
+	if _, err := aead.EncryptObjectEncoded(codecOrder{}, nil, encryption.ProtobufCodec, encryption.EncodingHex); !errors.Is(err, encryption.ErrCodecUnsupportedType) {
+		t.Errorf("Expected ErrCodecUnsupportedType, got: %v", err)
+	}
+	var order codecOrder
+	if err := encryption.ProtobufCodec.Unmarshal(nil, &order); !errors.Is(err, encryption.ErrCodecUnsupportedType) {
+		t.Errorf("Expected ErrCodecUnsupportedType from Unmarshal, got: %v", err)
+	}
+}
+
+func TestObjectCodecNames(t *testing.T) {
+	names := map[encryption.Codec]string{
+		encryption.JSONCodec:     "json",
+		encryption.GobCodec:      "gob",
+		encryption.ProtobufCodec: "protobuf",
+		encryption.MsgpackCodec:  "msgpack",
+		encryption.CBORCodec:     "cbor",
+	}
+	for c, name := range names {
+		if c.Name() != name {
+			t.Errorf("Expected codec name %q, got %q", name, c.Name())
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"xyz/test/helloworld/encryption"
+)
+
+func TestEncoding(t *testing.T) {
+	data := []byte{0xfb, 0xff, 0x00, 0x10, 'h', 'i'}
+	cases := []struct {
+		encoding encryption.Encoding
+		encoded  string
+	}{
+		{encryption.EncodingHex, "fbff00106869"},
+		{encryption.EncodingBase64, "+/8AEGhp"},
+		{encryption.EncodingBase64URL, "-_8AEGhp"},
+		{encryption.EncodingRaw, "\xfb\xff\x00\x10hi"},
+	}
+	for _, c := range cases {
+		encoded, err := c.encoding.Encode(data)
+		if err != nil {
+			t.Fatalf("%v: Encode failed: %v", c.encoding, err)
+		}
+		if encoded != c.encoded {
+			t.Errorf("%v: Expected %q, got %q", c.encoding, c.encoded, encoded)
+		}
+		decoded, err := c.encoding.Decode(encoded)
+		if err != nil {
+			t.Fatalf("%v: Decode failed: %v", c.encoding, err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("%v: Decoded data mismatch: %x", c.encoding, decoded)
+		}
+	}
+
+	// Base64URL output carries no padding
+	if encoded, _ := encryption.EncodingBase64URL.Encode([]byte("a")); encoded != "YQ" {
+		t.Errorf("Expected unpadded Base64URL, got %q", encoded)
+	}
+	if _, err := encryption.EncodingHex.Decode("zz"); err == nil {
+		t.Error("Expected error decoding invalid hex")
+	}
+
+	unknown := encryption.Encoding(99)
+	if _, err := unknown.Encode(data); !errors.Is(err, encryption.ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding, got: %v", err)
+	}
+	if _, err := unknown.Decode("00"); !errors.Is(err, encryption.ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding, got: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"xyz/test/helloworld/encryption"
+)
+
+func TestEnvelopeSealAndOpen(t *testing.T) {
+	recipient, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	// Sealing only needs the public key
+	encryptor, err := encryption.NewSM2Encryptor(recipient.PublicKeyHex())
+	if err != nil {
+		t.Fatalf("Failed to create SM2 encryptor: %v", err)
+	}
+
+	// 1 MiB payload exercises the bulk SM4 path
+	plaintext := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+
+	envelope, err := encryption.SealEnvelope(plaintext, encryptor)
+	if err != nil {
+		t.Fatalf("SealEnvelope failed: %v", err)
+	}
+
+	opened, err := encryption.OpenEnvelope(envelope, recipient)
+	if err != nil {
+		t.Fatalf("OpenEnvelope failed: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Error("Opened payload doesn't match original")
+	}
+}
+
+func TestEnvelopeMultipleRecipients(t *testing.T) {
+	alice, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	bob, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	eve, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	plaintext := []byte("Hello, SM2+SM4 envelope!")
+	envelope, err := encryption.SealEnvelope(plaintext, alice, bob)
+	if err != nil {
+		t.Fatalf("SealEnvelope failed: %v", err)
+	}
+
+	// The envelope is self-describing JSON
+	var env encryption.Envelope
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		t.Fatalf("Envelope is not valid JSON: %v", err)
+	}
+	if env.Version != encryption.EnvelopeVersion || env.Algorithm != encryption.EnvelopeAlgorithm || len(env.Recipients) != 2 {
+		t.Errorf("Unexpected envelope header: %+v", env)
+	}
+
+	for name, recipient := range map[string]*encryption.SM2{"alice": alice, "bob": bob} {
+		opened, err := encryption.OpenEnvelope(envelope, recipient)
+		if err != nil {
+			t.Fatalf("OpenEnvelope failed for %s: %v", name, err)
+		}
+		if !bytes.Equal(opened, plaintext) {
+			t.Errorf("Opened payload doesn't match original for %s", name)
+		}
+	}
+
+	if _, err := encryption.OpenEnvelope(envelope, eve); !errors.Is(err, encryption.ErrNoRecipient) {
+		t.Errorf("Expected ErrNoRecipient for non-recipient, got %v", err)
+	}
+}
+
+// TestEnvelopeErrorHandling tests error handling in envelope functions
+func TestEnvelopeErrorHandling(t *testing.T) {
+	recipient, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	// Test sealing without recipients
+	if _, err := encryption.SealEnvelope([]byte("test")); !errors.Is(err, encryption.ErrInvalidEnvelope) {
+		t.Errorf("Expected ErrInvalidEnvelope without recipients, got %v", err)
+	}
+
+	// Test opening data that is not an envelope
+	if _, err := encryption.OpenEnvelope([]byte("invalid"), recipient); !errors.Is(err, encryption.ErrInvalidEnvelope) {
+		t.Errorf("Expected ErrInvalidEnvelope for invalid data, got %v", err)
+	}
+
+	envelope, err := encryption.SealEnvelope([]byte("Hello, tamper!"), recipient)
+	if err != nil {
+		t.Fatalf("SealEnvelope failed: %v", err)
+	}
+
+	// Test tampered ciphertext
+	var env encryption.Envelope
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		t.Fatalf("Envelope is not valid JSON: %v", err)
+	}
+	env.Ciphertext[0] ^= 0x01
+	tampered, _ := json.Marshal(env)
+	if _, err := encryption.OpenEnvelope(tampered, recipient); !errors.Is(err, encryption.ErrEnvelopeAuth) {
+		t.Errorf("Expected ErrEnvelopeAuth for tampered ciphertext, got %v", err)
+	}
+
+	// Test unsupported version
+	env.Ciphertext[0] ^= 0x01
+	env.Version = 99
+	unsupported, _ := json.Marshal(env)
+	if _, err := encryption.OpenEnvelope(unsupported, recipient); !errors.Is(err, encryption.ErrInvalidEnvelope) {
+		t.Errorf("Expected ErrInvalidEnvelope for unsupported version, got %v", err)
+	}
+
+	// Nil recipients and recipients without keys are rejected instead of panicking
+	if _, err := encryption.SealEnvelope([]byte("test"), nil); !errors.Is(err, encryption.ErrPublicKeyRequired) {
+		t.Errorf("Expected ErrPublicKeyRequired for nil recipient, got %v", err)
+	}
+	if _, err := encryption.SealEnvelope([]byte("test"), recipient, &encryption.SM2{}); !errors.Is(err, encryption.ErrPublicKeyRequired) {
+		t.Errorf("Expected ErrPublicKeyRequired for recipient without public key, got %v", err)
+	}
+	if keyID := (&encryption.SM2{}).KeyID(); keyID != "" {
+		t.Errorf("Expected empty KeyID without public key, got %q", keyID)
+	}
+	if _, err := encryption.OpenEnvelope(envelope, nil); !errors.Is(err, encryption.ErrPrivateKeyRequired) {
+		t.Errorf("Expected ErrPrivateKeyRequired for nil recipient, got %v", err)
+	}
+}
+
+func TestEnvelopeHeaderTampering(t *testing.T) {
+	alice, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	bob, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	envelope, err := encryption.SealEnvelope([]byte("Hello, header!"), alice, bob)
+	if err != nil {
+		t.Fatalf("SealEnvelope failed: %v", err)
+	}
+
+	cases := map[string]func(env *encryption.Envelope){
+		"DroppedRecipient": func(env *encryption.Envelope) { env.Recipients = env.Recipients[:1] },
+		"ChangedKeyID":     func(env *encryption.Envelope) { env.Recipients[1].KeyID = "00" },
+		"ChangedIV":        func(env *encryption.Envelope) { env.IV[0] ^= 0x01 },
+		"AddedRecipient": func(env *encryption.Envelope) {
+			env.Recipients = append(env.Recipients, encryption.EnvelopeRecipient{KeyID: "mallory", EncryptedKey: []byte{1}})
+		},
+	}
+	for name, tamper := range cases {
+		var env encryption.Envelope
+		if err := json.Unmarshal(envelope, &env); err != nil {
+			t.Fatalf("Envelope is not valid JSON: %v", err)
+		}
+		tamper(&env)
+		tampered, _ := json.Marshal(env)
+		if _, err := encryption.OpenEnvelope(tampered, alice); !errors.Is(err, encryption.ErrEnvelopeAuth) {
+			t.Errorf("%s: Expected ErrEnvelopeAuth, got %v", name, err)
+		}
+	}
+
+	// Version 1 envelopes did not authenticate the header and are no longer accepted
+	var env encryption.Envelope
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		t.Fatalf("Envelope is not valid JSON: %v", err)
+	}
+	env.Version = 1
+	v1, _ := json.Marshal(env)
+	if _, err := encryption.OpenEnvelope(v1, alice); !errors.Is(err, encryption.ErrInvalidEnvelope) {
+		t.Errorf("Expected ErrInvalidEnvelope for version 1, got %v", err)
+	}
+}
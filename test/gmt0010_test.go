@@ -0,0 +1,334 @@
+package test
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+
+	"github.com/tjfoc/gmsm/x509"
+
+	"xyz/test/helloworld/encryption"
+)
+
+// Certificates in testdata were generated with OpenSSL 3:
+//
+//	openssl req -new -x509 -key sm2_pkcs8.pem -sm3 -sigopt distid:1234567812345678 \
+//		-subj "/CN=SM2 Test Signer" -days 36500 -out sm2_cert.pem
+//	openssl genpkey -algorithm SM2 -out sm2_recipient_key.pem
+//	openssl req -new -x509 -key sm2_recipient_key.pem -sm3 -sigopt distid:1234567812345678 \
+//		-subj "/CN=SM2 Test Recipient" -days 36500 -out sm2_recipient_cert.pem
+//
+// OpenSSL cannot emit GM/T 0010 structures, so gmt0010_signed.der was produced by
+// this package and checked with `openssl asn1parse -inform DER`; it guards the
+// wire format against accidental changes.
+//
+// gmt0010_openssl_signed.der carries authenticated attributes in the layout Tongsuo/GmSSL
+// emit; gmt0010_openssl_signed.py assembles it around an SM3 digest from `openssl dgst -sm3`
+// and an SM2 signature from `openssl pkeyutl -sign -rawin -digest sm3 -pkeyopt distid:...`.
+// OpenSSL 3.0 `smime -verify` accepts its messageDigest but omits ZA when checking the signature.
+
+func loadGMT0010Fixtures(t *testing.T) (signer *encryption.SM2, signerCert *x509.Certificate, recipient *encryption.SM2, recipientCert *x509.Certificate) {
+	t.Helper()
+	var err error
+	if signer, err = encryption.NewSM2FromPEM(readFixture(t, "sm2_pkcs8.pem"), nil); err != nil {
+		t.Fatalf("Failed to load signer key: %v", err)
+	}
+	if signerCert, err = x509.ReadCertificateFromPem(readFixture(t, "sm2_cert.pem")); err != nil {
+		t.Fatalf("Failed to load signer certificate: %v", err)
+	}
+	if recipient, err = encryption.NewSM2FromPEM(readFixture(t, "sm2_recipient_key.pem"), nil); err != nil {
+		t.Fatalf("Failed to load recipient key: %v", err)
+	}
+	if recipientCert, err = x509.ReadCertificateFromPem(readFixture(t, "sm2_recipient_cert.pem")); err != nil {
+		t.Fatalf("Failed to load recipient certificate: %v", err)
+	}
+	return
+}
+
+func TestSignedDataAttached(t *testing.T) {
+	signer, signerCert, _, _ := loadGMT0010Fixtures(t)
+	content := []byte("GM/T 0010 signed content")
+
+	der, err := encryption.EncodeSignedData(content, signer, signerCert, nil)
+	if err != nil {
+		t.Fatalf("EncodeSignedData failed: %v", err)
+	}
+	sd, err := encryption.DecodeSignedData(der)
+	if err != nil {
+		t.Fatalf("DecodeSignedData failed: %v", err)
+	}
+	if !bytes.Equal(sd.Content, content) {
+		t.Errorf("Content mismatch. Expected: %s, Got: %s", content, sd.Content)
+	}
+	if len(sd.Certificates) != 1 || !sd.Certificates[0].Equal(signerCert) {
+		t.Error("Signer certificate should be embedded")
+	}
+	signers, err := sd.Verify()
+	if err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+	if len(signers) != 1 || !signers[0].Equal(signerCert) {
+		t.Error("Verify should return the signer certificate")
+	}
+
+	// Tampered content must fail verification
+	sd.Content = []byte("GM/T 0010 signed c0ntent")
+	if _, err := sd.Verify(); !errors.Is(err, encryption.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for tampered content, got: %v", err)
+	}
+}
+
+func TestSignedDataDetached(t *testing.T) {
+	signer, signerCert, _, _ := loadGMT0010Fixtures(t)
+	content := []byte("detached content")
+
+	der, err := encryption.EncodeSignedData(content, signer, signerCert, &encryption.PKCS7Options{
+		Detached:              true,
+		OmitSignerCertificate: true,
+	})
+	if err != nil {
+		t.Fatalf("EncodeSignedData failed: %v", err)
+	}
+	sd, err := encryption.DecodeSignedData(der)
+	if err != nil {
+		t.Fatalf("DecodeSignedData failed: %v", err)
+	}
+	if len(sd.Content) != 0 {
+		t.Error("Detached signature should not carry content")
+	}
+	if _, err := sd.VerifyDetached(content); !errors.Is(err, encryption.ErrPKCS7SignerNotFound) {
+		t.Errorf("Expected ErrPKCS7SignerNotFound without certificate, got: %v", err)
+	}
+
+	// The verifier supplies the signer certificate out of band
+	sd.Certificates = append(sd.Certificates, signerCert)
+	if _, err := sd.VerifyDetached(content); err != nil {
+		t.Errorf("VerifyDetached failed: %v", err)
+	}
+	if _, err := sd.VerifyDetached([]byte("other content")); !errors.Is(err, encryption.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for wrong content, got: %v", err)
+	}
+}
+
+func TestSignedDataFixture(t *testing.T) {
+	sd, err := encryption.DecodeSignedData(readFixture(t, "gmt0010_signed.der"))
+	if err != nil {
+		t.Fatalf("DecodeSignedData failed: %v", err)
+	}
+	if string(sd.Content) != "Hello, GM/T 0010!" {
+		t.Errorf("Unexpected content: %s", sd.Content)
+	}
+	if _, err := sd.Verify(); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+}
+
+func TestSignedDataOpenSSLAttributes(t *testing.T) {
+	_, signerCert, _, _ := loadGMT0010Fixtures(t)
+	der := readFixture(t, "gmt0010_openssl_signed.der")
+	sd, err := encryption.DecodeSignedData(der)
+	if err != nil {
+		t.Fatalf("DecodeSignedData failed: %v", err)
+	}
+	if string(sd.Content) != "Hello, GM/T 0010 from OpenSSL!" {
+		t.Errorf("Unexpected content: %s", sd.Content)
+	}
+	signers, err := sd.VerifyWithRoots([]*x509.Certificate{signerCert})
+	if err != nil {
+		t.Fatalf("VerifyWithRoots failed: %v", err)
+	}
+	if len(signers) != 1 || !signers[0].Equal(signerCert) {
+		t.Error("Expected the OpenSSL signer certificate")
+	}
+
+	// Content that no longer matches the messageDigest attribute
+	if _, err := sd.VerifyDetached([]byte("Hello, GM/T 0010 from OpenSSL?")); !errors.Is(err, encryption.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for tampered content, got: %v", err)
+	}
+	tampered, err := encryption.DecodeSignedData(bytes.Replace(der, []byte("from OpenSSL!"), []byte("from OpenSSL?"), 1))
+	if err != nil {
+		t.Fatalf("DecodeSignedData failed: %v", err)
+	}
+	if _, err := tampered.Verify(); !errors.Is(err, encryption.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for tampered embedded content, got: %v", err)
+	}
+
+	// Signed attributes altered while the messageDigest still matches
+	if !bytes.Contains(der, []byte("260101000000Z")) {
+		t.Fatal("Fixture is missing its signingTime attribute")
+	}
+	tampered, err = encryption.DecodeSignedData(bytes.Replace(der, []byte("260101000000Z"), []byte("260102000000Z"), 1))
+	if err != nil {
+		t.Fatalf("DecodeSignedData failed: %v", err)
+	}
+	if _, err := tampered.Verify(); !errors.Is(err, encryption.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for tampered attributes, got: %v", err)
+	}
+}
+
+func TestEnvelopedData(t *testing.T) {
+	_, signerCert, recipient, recipientCert := loadGMT0010Fixtures(t)
+	signerAsRecipient, err := encryption.NewSM2FromPEM(readFixture(t, "sm2_pkcs8.pem"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load key: %v", err)
+	}
+	content := bytes.Repeat([]byte("enveloped "), 100)
+
+	der, err := encryption.EncodeEnvelopedData(content, recipientCert, signerCert)
+	if err != nil {
+		t.Fatalf("EncodeEnvelopedData failed: %v", err)
+	}
+
+	opened, err := encryption.DecodeEnvelopedData(der, recipient, recipientCert)
+	if err != nil {
+		t.Fatalf("DecodeEnvelopedData failed: %v", err)
+	}
+	if !bytes.Equal(opened, content) {
+		t.Error("Decrypted content doesn't match original")
+	}
+
+	// Without a certificate every recipient is tried
+	opened, err = encryption.DecodeEnvelopedData(der, signerAsRecipient, nil)
+	if err != nil {
+		t.Fatalf("DecodeEnvelopedData without certificate failed: %v", err)
+	}
+	if !bytes.Equal(opened, content) {
+		t.Error("Decrypted content doesn't match original")
+	}
+
+	// A key that is not a recipient
+	outsider, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	if _, err := encryption.DecodeEnvelopedData(der, outsider, nil); !errors.Is(err, encryption.ErrPKCS7NotRecipient) {
+		t.Errorf("Expected ErrPKCS7NotRecipient, got: %v", err)
+	}
+}
+
+func TestSignedAndEnvelopedData(t *testing.T) {
+	signer, signerCert, recipient, recipientCert := loadGMT0010Fixtures(t)
+	content := []byte("signed and enveloped")
+
+	der, err := encryption.EncodeSignedAndEnvelopedData(content, signer, signerCert, recipientCert)
+	if err != nil {
+		t.Fatalf("EncodeSignedAndEnvelopedData failed: %v", err)
+	}
+	sd, err := encryption.DecodeSignedAndEnvelopedData(der, recipient, recipientCert)
+	if err != nil {
+		t.Fatalf("DecodeSignedAndEnvelopedData failed: %v", err)
+	}
+	if !bytes.Equal(sd.Content, content) {
+		t.Errorf("Content mismatch. Expected: %s, Got: %s", content, sd.Content)
+	}
+	if _, err := sd.Verify(); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+}
+
+func TestGMT0010ErrorHandling(t *testing.T) {
+	signer, signerCert, recipient, _ := loadGMT0010Fixtures(t)
+
+	signed, err := encryption.EncodeSignedData([]byte("content"), signer, signerCert, nil)
+	if err != nil {
+		t.Fatalf("EncodeSignedData failed: %v", err)
+	}
+	if _, err := encryption.DecodeEnvelopedData(signed, recipient, nil); !errors.Is(err, encryption.ErrInvalidPKCS7) {
+		t.Errorf("Expected ErrInvalidPKCS7 for wrong content type, got: %v", err)
+	}
+	if _, err := encryption.DecodeSignedData([]byte("not asn1")); !errors.Is(err, encryption.ErrInvalidPKCS7) {
+		t.Errorf("Expected ErrInvalidPKCS7 for garbage, got: %v", err)
+	}
+	if _, err := encryption.DecodeSignedData(append(signed, 0x00)); !errors.Is(err, encryption.ErrInvalidPKCS7) {
+		t.Errorf("Expected ErrInvalidPKCS7 for trailing data, got: %v", err)
+	}
+	enveloped, err := encryption.EncodeEnvelopedData([]byte("content"), signerCert)
+	if err != nil {
+		t.Fatalf("EncodeEnvelopedData failed: %v", err)
+	}
+	if _, err := encryption.DecodeEnvelopedData(enveloped, nil, nil); !errors.Is(err, encryption.ErrPrivateKeyRequired) {
+		t.Errorf("Expected ErrPrivateKeyRequired for nil recipient, got: %v", err)
+	}
+	signedEnveloped, err := encryption.EncodeSignedAndEnvelopedData([]byte("content"), signer, signerCert, signerCert)
+	if err != nil {
+		t.Fatalf("EncodeSignedAndEnvelopedData failed: %v", err)
+	}
+	if _, err := encryption.DecodeSignedAndEnvelopedData(signedEnveloped, nil, signerCert); !errors.Is(err, encryption.ErrPrivateKeyRequired) {
+		t.Errorf("Expected ErrPrivateKeyRequired for nil recipient, got: %v", err)
+	}
+	publicOnly, err := encryption.NewSM2FromCertificate(signerCert)
+	if err != nil {
+		t.Fatalf("NewSM2FromCertificate failed: %v", err)
+	}
+	if _, err := encryption.DecodeEnvelopedData(enveloped, publicOnly, nil); !errors.Is(err, encryption.ErrPrivateKeyRequired) {
+		t.Errorf("Expected ErrPrivateKeyRequired for public-key-only recipient, got: %v", err)
+	}
+	if _, err := encryption.EncodeEnvelopedData([]byte("content")); !errors.Is(err, encryption.ErrInvalidPKCS7) {
+		t.Errorf("Expected ErrInvalidPKCS7 without recipients, got: %v", err)
+	}
+
+	verifier, err := encryption.NewSM2Encryptor(signer.PublicKeyHex())
+	if err != nil {
+		t.Fatalf("Failed to create SM2 encryptor: %v", err)
+	}
+	if _, err := encryption.EncodeSignedData([]byte("content"), verifier, signerCert, nil); !errors.Is(err, encryption.ErrPrivateKeyRequired) {
+		t.Errorf("Expected ErrPrivateKeyRequired, got: %v", err)
+	}
+}
+
+func TestSignedDataVerifyWithRoots(t *testing.T) {
+	ca := newTestRootCA(t)
+	intermediateKey, intermediateCert := ca.issue(t, &encryption.CertificateOptions{
+		Subject: pkix.Name{CommonName: "SM2 Test Intermediate CA"},
+		IsCA:    true,
+	})
+	intermediate := &testCA{key: intermediateKey, cert: intermediateCert}
+	signer, signerCert := intermediate.issue(t, &encryption.CertificateOptions{
+		Subject: pkix.Name{CommonName: "SM2 Trusted Signer"},
+	})
+	content := []byte("signed by a trusted signer")
+
+	der, err := encryption.EncodeSignedData(content, signer, signerCert, &encryption.PKCS7Options{
+		Certificates: []*x509.Certificate{intermediateCert},
+	})
+	if err != nil {
+		t.Fatalf("EncodeSignedData failed: %v", err)
+	}
+	sd, err := encryption.DecodeSignedData(der)
+	if err != nil {
+		t.Fatalf("DecodeSignedData failed: %v", err)
+	}
+	signers, err := sd.VerifyWithRoots([]*x509.Certificate{ca.cert})
+	if err != nil {
+		t.Fatalf("VerifyWithRoots failed: %v", err)
+	}
+	if len(signers) != 1 || signers[0].Subject.CommonName != "SM2 Trusted Signer" {
+		t.Errorf("Unexpected signers: %v", signers)
+	}
+
+	// A self-signed signer passes Verify but is rejected against the trusted roots
+	forger, forgerCert, _, _ := loadGMT0010Fixtures(t)
+	forged, err := encryption.EncodeSignedData(content, forger, forgerCert, nil)
+	if err != nil {
+		t.Fatalf("EncodeSignedData failed: %v", err)
+	}
+	sd, err = encryption.DecodeSignedData(forged)
+	if err != nil {
+		t.Fatalf("DecodeSignedData failed: %v", err)
+	}
+	if _, err := sd.Verify(); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if _, err := sd.VerifyWithRoots([]*x509.Certificate{ca.cert}); !errors.Is(err, encryption.ErrCertificateVerify) {
+		t.Errorf("Expected ErrCertificateVerify for untrusted signer, got: %v", err)
+	}
+	if _, err := sd.VerifyDetachedWithRoots(content, nil); !errors.Is(err, encryption.ErrCertificateVerify) {
+		t.Errorf("Expected ErrCertificateVerify without roots, got: %v", err)
+	}
+
+	// Signature errors are still reported before the chain is checked
+	if _, err := sd.VerifyDetachedWithRoots([]byte("other"), []*x509.Certificate{ca.cert}); !errors.Is(err, encryption.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got: %v", err)
+	}
+}
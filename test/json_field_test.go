@@ -0,0 +1,359 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"xyz/test/helloworld/encryption"
+)
+
+const jsonFieldDocument = `{
+  "name": "张三",
+  "idCard": "110101199003074512",
+  "age": 34,
+  "contacts": [
+    {"type": "mobile", "phone": "13800138000"},
+    {"type": "office", "phone": 861012345678, "ext": null}
+  ],
+  "address": {"city": "北京", "detail": {"street": "长安街1号", "phone": "010-12345678"}},
+  "note": "<b>不加密</b>"
+}`
+
+func newJSONFieldEncryptor(t *testing.T, selectors ...string) *encryption.JSONFieldEncryptor {
+	t.Helper()
+	sm4, err := encryption.FromHex("0123456789ABCDEFFEDCBA9876543210", "", encryption.WithRandomIV())
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	fe, err := encryption.NewJSONFieldEncryptor(sm4, selectors...)
+	if err != nil {
+		t.Fatalf("NewJSONFieldEncryptor failed: %v", err)
+	}
+	return fe
+}
+
+// jsonEqual compares documents ignoring whitespace and key order
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", b, err)
+	}
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return bytes.Equal(ja, jb)
+}
+
+func TestJSONFieldEncryption(t *testing.T) {
+	fe := newJSONFieldEncryptor(t, "$.idCard", "$.contacts[*].phone", "$.address")
+	encrypted, err := fe.Encrypt([]byte(jsonFieldDocument))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(encrypted, &doc); err != nil {
+		t.Fatalf("Encrypted document is not valid JSON: %v", err)
+	}
+	for _, value := range []any{
+		doc["idCard"],
+		doc["address"],
+		doc["contacts"].([]any)[0].(map[string]any)["phone"],
+		doc["contacts"].([]any)[1].(map[string]any)["phone"],
+	} {
+		if s, ok := value.(string); !ok || !strings.HasPrefix(s, encryption.JSONFieldPrefix) {
+			t.Errorf("Expected tagged ciphertext, got %v", value)
+		}
+	}
+	// Unselected fields stay readable
+	if doc["name"] != "张三" || doc["age"] != float64(34) || doc["note"] != "<b>不加密</b>" {
+		t.Errorf("Unselected fields changed: %s", encrypted)
+	}
+	if bytes.Contains(encrypted, []byte("13800138000")) || bytes.Contains(encrypted, []byte("长安街")) {
+		t.Errorf("Sensitive data left in plaintext: %s", encrypted)
+	}
+
+	decrypted, err := fe.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !jsonEqual(t, decrypted, []byte(jsonFieldDocument)) {
+		t.Errorf("Decrypted document mismatch: %s", decrypted)
+	}
+	// The number-typed phone is restored as a number, not a string
+	if !bytes.Contains(decrypted, []byte(`"phone":861012345678`)) {
+		t.Errorf("Field type not restored: %s", decrypted)
+	}
+
+	// Input is always treated as plaintext: encrypting twice adds a layer that one Decrypt removes
+	again, err := fe.Encrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if bytes.Equal(again, encrypted) {
+		t.Error("Already encrypted fields should be encrypted again")
+	}
+	once, err := fe.Decrypt(again)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !jsonEqual(t, once, encrypted) {
+		t.Errorf("Decrypt should remove one layer: %s", once)
+	}
+}
+
+func TestJSONFieldOverlappingSelectors(t *testing.T) {
+	doc := []byte(`{"user":{"phone":"123","name":"x"}}`)
+	for _, selectors := range [][]string{{"$.user.phone", "$.user"}, {"$.user", "$.user.phone"}, {"$..phone", "$.user"}} {
+		fe := newJSONFieldEncryptor(t, selectors...)
+		encrypted, err := fe.Encrypt(doc)
+		if err != nil {
+			t.Fatalf("%v: Encrypt failed: %v", selectors, err)
+		}
+		if bytes.Contains(encrypted, []byte("123")) {
+			t.Errorf("%v: Sensitive data left in plaintext: %s", selectors, encrypted)
+		}
+		decrypted, err := fe.Decrypt(encrypted)
+		if err != nil {
+			t.Fatalf("%v: Decrypt failed: %v", selectors, err)
+		}
+		if !bytes.Equal(decrypted, doc) {
+			t.Errorf("%v: Decrypted document mismatch: %s", selectors, decrypted)
+		}
+
+		var streamed bytes.Buffer
+		if err := fe.DecryptArray(&streamed, bytes.NewReader(append(append([]byte{'['}, encrypted...), ']'))); err != nil {
+			t.Fatalf("%v: DecryptArray failed: %v", selectors, err)
+		}
+		if streamed.String() != "["+string(doc)+"]" {
+			t.Errorf("%v: Decrypted array mismatch: %s", selectors, streamed.String())
+		}
+	}
+}
+
+func TestJSONFieldPreservesLayout(t *testing.T) {
+	fe := newJSONFieldEncryptor(t, "$.b.secret")
+	doc := `{"z": 1, "a": {"keep":  [1, 2]}, "b": {"secret": "x", "y": 1.50}}`
+	encrypted, err := fe.Encrypt([]byte(doc))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	// Key order is kept and subtrees off the selected path are copied byte for byte
+	if !bytes.HasPrefix(encrypted, []byte(`{"z":1,"a":{"keep":  [1, 2]},"b":{"secret":"sm4:`)) ||
+		!bytes.HasSuffix(encrypted, []byte(`","y":1.50}}`)) {
+		t.Errorf("Unexpected layout: %s", encrypted)
+	}
+
+	// Documents without matches come back unchanged
+	unchanged, err := fe.Encrypt([]byte(`{"other": [1,  2]}`))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if string(unchanged) != `{"other": [1,  2]}` {
+		t.Errorf("Unexpected output: %s", unchanged)
+	}
+}
+
+func TestJSONFieldSelectors(t *testing.T) {
+	doc := []byte(`{"a": {"phone": "1", "b": [{"phone": "2"}, {"phone": "3", "c": {"phone": "4"}}]}, "list": [10, 20, 30], "odd key.[]": "5", "phone": null}`)
+	cases := []struct {
+		selector string
+		count    int
+	}{
+		{"$..phone", 4},
+		{"$.a.b[*].phone", 2},
+		{"$.a.b[1].phone", 1},
+		{"$.a.b[1]..phone", 2},
+		{"$.list[2]", 1},
+		{"$.list[*]", 3},
+		{"$.list.*", 3},
+		{"$['odd key.[]']", 1},
+		{`$["a"]["phone"]`, 1},
+		{"$..[0]", 2},
+		{"$.a.*", 2},
+		{"$.missing.phone", 0},
+		{"$.a.b.phone", 0},
+		{"$", 1},
+	}
+	for _, c := range cases {
+		fe := newJSONFieldEncryptor(t, c.selector)
+		encrypted, err := fe.Encrypt(doc)
+		if err != nil {
+			t.Fatalf("%s: Encrypt failed: %v", c.selector, err)
+		}
+		if count := bytes.Count(encrypted, []byte(encryption.JSONFieldPrefix)); count != c.count {
+			t.Errorf("%s: Expected %d encrypted fields, got %d: %s", c.selector, c.count, count, encrypted)
+		}
+		decrypted, err := fe.Decrypt(encrypted)
+		if err != nil {
+			t.Fatalf("%s: Decrypt failed: %v", c.selector, err)
+		}
+		if !jsonEqual(t, decrypted, doc) {
+			t.Errorf("%s: Decrypted document mismatch: %s", c.selector, decrypted)
+		}
+	}
+
+	sm4, err := encryption.FromHex("0123456789ABCDEFFEDCBA9876543210", "", encryption.WithRandomIV())
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	for _, selector := range []string{"", "a.b", "$.", "$..", "$...a", "$[", "$[x]", "$[-1]", "$['a]", "$['a'", "$a", "$.a[0"} {
+		if _, err := encryption.NewJSONFieldEncryptor(sm4, selector); !errors.Is(err, encryption.ErrInvalidSelector) {
+			t.Errorf("%q: Expected ErrInvalidSelector, got: %v", selector, err)
+		}
+	}
+	if _, err := encryption.NewJSONFieldEncryptor(sm4); !errors.Is(err, encryption.ErrInvalidSelector) {
+		t.Errorf("Expected ErrInvalidSelector without selectors, got: %v", err)
+	}
+}
+
+func TestJSONFieldArrayStream(t *testing.T) {
+	fe := newJSONFieldEncryptor(t, "$.idCard", "$.contacts[*].phone")
+	var input bytes.Buffer
+	input.WriteString("[\n")
+	for i := 0; i < 200; i++ {
+		if i > 0 {
+			input.WriteString(",\n")
+		}
+		input.WriteString(jsonFieldDocument)
+	}
+	input.WriteString("\n]")
+
+	var encrypted bytes.Buffer
+	if err := fe.EncryptArray(&encrypted, iotest.HalfReader(bytes.NewReader(input.Bytes()))); err != nil {
+		t.Fatalf("EncryptArray failed: %v", err)
+	}
+	var records []map[string]any
+	if err := json.Unmarshal(encrypted.Bytes(), &records); err != nil || len(records) != 200 {
+		t.Fatalf("Encrypted stream is not a 200-element array: %v", err)
+	}
+	if bytes.Contains(encrypted.Bytes(), []byte("110101199003074512")) {
+		t.Error("Sensitive data left in plaintext")
+	}
+
+	var decrypted bytes.Buffer
+	if err := fe.DecryptArray(&decrypted, bytes.NewReader(encrypted.Bytes())); err != nil {
+		t.Fatalf("DecryptArray failed: %v", err)
+	}
+	if !jsonEqual(t, decrypted.Bytes(), input.Bytes()) {
+		t.Error("Decrypted array mismatch")
+	}
+
+	// Stream selectors are relative to each element; the whole-document API needs $[*]
+	whole := newJSONFieldEncryptor(t, "$[*].idCard", "$[*].contacts[*].phone")
+	decryptedWhole, err := whole.Decrypt(encrypted.Bytes())
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !jsonEqual(t, decryptedWhole, input.Bytes()) {
+		t.Error("Whole-document decryption of the stream output mismatch")
+	}
+
+	var empty bytes.Buffer
+	if err := fe.EncryptArray(&empty, strings.NewReader(" [ ] ")); err != nil || empty.String() != "[]" {
+		t.Errorf("Empty array: %v %q", err, empty.String())
+	}
+	for _, bad := range []string{`{"a":1}`, `[1, 2`, `[1] [2]`, ``} {
+		if err := fe.EncryptArray(&bytes.Buffer{}, strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: Expected error", bad)
+		}
+	}
+	if err := fe.EncryptArray(&bytes.Buffer{}, strings.NewReader(`{"a":1}`)); !errors.Is(err, encryption.ErrNotJSONArray) {
+		t.Errorf("Expected ErrNotJSONArray, got: %v", err)
+	}
+}
+
+func TestJSONFieldDecryptErrors(t *testing.T) {
+	fe := newJSONFieldEncryptor(t, "$.idCard")
+	encrypted, err := fe.Encrypt([]byte(`{"idCard": "110101199003074512"}`))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	// Untagged values at selected paths are left as they are, e.g. records written before encryption
+	plain := []byte(`{"idCard": "110101199003074512"}`)
+	if out, err := fe.Decrypt(plain); err != nil || !bytes.Equal(out, plain) {
+		t.Errorf("Plaintext field should pass through: %s %v", out, err)
+	}
+
+	other, err := encryption.FromHex("FEDCBA98765432100123456789ABCDEF", "", encryption.WithRandomIV())
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	wrongKey, err := encryption.NewJSONFieldEncryptor(other, "$.idCard")
+	if err != nil {
+		t.Fatalf("NewJSONFieldEncryptor failed: %v", err)
+	}
+	if _, err := wrongKey.Decrypt(encrypted); err == nil || !strings.Contains(err.Error(), "$.idCard") {
+		t.Errorf("Expected decryption error naming the selector, got: %v", err)
+	}
+
+	// Well-formed ciphertext that does not decrypt is an error, not plaintext
+	tampered := []byte(`{"idCard": "sm4:` + strings.Repeat("A", 43) + "=" + `"}`)
+	if _, err := fe.Decrypt(tampered); err == nil {
+		t.Error("Expected error for ciphertext that does not decrypt")
+	}
+	if _, err := fe.Encrypt([]byte(`{"idCard": `)); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestJSONFieldPrefixedPlaintext(t *testing.T) {
+	fe := newJSONFieldEncryptor(t, "$.idCard")
+
+	// User input that merely starts with the marker must still be encrypted
+	for _, value := range []string{"sm4:110101199003077777", "sm4:", "sm4:!!!", "sm4:" + strings.Repeat("A", 43) + "="} {
+		doc, _ := json.Marshal(map[string]string{"idCard": value})
+		encrypted, err := fe.Encrypt(doc)
+		if err != nil {
+			t.Fatalf("%q: Encrypt failed: %v", value, err)
+		}
+		if bytes.Contains(encrypted, []byte(strings.TrimPrefix(value, encryption.JSONFieldPrefix))) && value != encryption.JSONFieldPrefix {
+			t.Errorf("%q: Plaintext left in encrypted document: %s", value, encrypted)
+		}
+		decrypted, err := fe.Decrypt(encrypted)
+		if err != nil {
+			t.Fatalf("%q: Decrypt failed: %v", value, err)
+		}
+		if !bytes.Equal(decrypted, doc) {
+			t.Errorf("%q: Decrypted document mismatch: %s", value, decrypted)
+		}
+	}
+
+	// Ciphertext copied from another record is not passed through as if already encrypted
+	other, err := fe.Encrypt([]byte(`{"idCard":"110101199003074512"}`))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	var copied map[string]string
+	if err := json.Unmarshal(other, &copied); err != nil {
+		t.Fatalf("Encrypted document is not valid JSON: %v", err)
+	}
+	reencrypted, err := fe.Encrypt(other)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if bytes.Contains(reencrypted, []byte(copied["idCard"])) {
+		t.Errorf("Copied ciphertext passed through unchanged: %s", reencrypted)
+	}
+	decrypted, err := fe.Decrypt(reencrypted)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, other) {
+		t.Errorf("Decrypt should return the copied value itself: %s", decrypted)
+	}
+
+	// Unencrypted records whose value is not a well-formed ciphertext pass through Decrypt
+	plain := []byte(`{"idCard":"sm4:110101199003077777"}`)
+	if out, err := fe.Decrypt(plain); err != nil || !bytes.Equal(out, plain) {
+		t.Errorf("Prefixed plaintext should pass through: %s %v", out, err)
+	}
+}
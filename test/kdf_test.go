@@ -0,0 +1,165 @@
+package test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"xyz/test/helloworld/encryption"
+)
+
+// Expected values were computed with OpenSSL 3:
+//
+//	openssl kdf -keylen <n> -kdfopt digest:SM3 -kdfopt hexsecret:<z> X963KDF
+//	openssl kdf -keylen <n> -kdfopt digest:SM3 -kdfopt pass:<p> -kdfopt salt:<s> -kdfopt iter:<i> PBKDF2
+//	openssl kdf -keylen <n> -kdfopt digest:SM3 -kdfopt hexkey:<k> -kdfopt hexsalt:<s> -kdfopt hexinfo:<i> HKDF
+//
+// X9.63 KDF without shared info is identical to the GM/T 0003 KDF.
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Invalid hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestSM3KDF(t *testing.T) {
+	z := mustDecodeHex(t, "00112233445566778899aabbccddeeff")
+	expected := "e29ff8c097825e90a953629233499e0e02def62c5f7e2cb4f12550f6b7196595" +
+		"e2fd339ad4ee446556de24698d2ea04d39a23ade6453c878465a6d844907a6b8"
+
+	key, err := encryption.SM3KDF(64, z)
+	if err != nil {
+		t.Fatalf("SM3KDF failed: %v", err)
+	}
+	if hex.EncodeToString(key) != expected {
+		t.Errorf("SM3KDF mismatch. Expected: %s, Got: %x", expected, key)
+	}
+
+	// Shorter outputs are prefixes; split inputs are concatenated
+	key, err = encryption.SM3KDF(19, z[:5], z[5:])
+	if err != nil {
+		t.Fatalf("SM3KDF failed: %v", err)
+	}
+	if hex.EncodeToString(key) != expected[:38] {
+		t.Errorf("SM3KDF prefix mismatch. Expected: %s, Got: %x", expected[:38], key)
+	}
+
+	if _, err := encryption.SM3KDF(0, z); !errors.Is(err, encryption.ErrInvalidKeyLength) {
+		t.Errorf("Expected ErrInvalidKeyLength, got: %v", err)
+	}
+}
+
+func TestPBKDF2SM3(t *testing.T) {
+	cases := []struct {
+		iterations int
+		keyLen     int
+		expected   string
+	}{
+		{1, 32, "4612f922a1fdcefaf4312fc6f8f3322b489cbf24f2ea361b44c2bd8fa2c6dcb0"},
+		{4096, 40, "b6e8f2074c87432b78f62e5ced980fdff89e86af2f693dab1638e2b3683045dd844438500eead50c"},
+	}
+	for _, c := range cases {
+		key, err := encryption.PBKDF2SM3([]byte("password"), []byte("salt"), c.iterations, c.keyLen)
+		if err != nil {
+			t.Fatalf("PBKDF2SM3 failed: %v", err)
+		}
+		if hex.EncodeToString(key) != c.expected {
+			t.Errorf("PBKDF2SM3(iter=%d) mismatch. Expected: %s, Got: %x", c.iterations, c.expected, key)
+		}
+	}
+
+	if _, err := encryption.PBKDF2SM3([]byte("password"), []byte("salt"), 0, 32); err == nil {
+		t.Error("Expected error for zero iterations")
+	}
+	if _, err := encryption.PBKDF2SM3([]byte("password"), []byte("salt"), 1, 0); !errors.Is(err, encryption.ErrInvalidKeyLength) {
+		t.Errorf("Expected ErrInvalidKeyLength, got: %v", err)
+	}
+}
+
+func TestHKDFSM3(t *testing.T) {
+	// RFC 5869 test case 1 and 3 inputs
+	ikm := mustDecodeHex(t, "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+	salt := mustDecodeHex(t, "000102030405060708090a0b0c")
+	info := mustDecodeHex(t, "f0f1f2f3f4f5f6f7f8f9")
+
+	cases := []struct {
+		name     string
+		salt     []byte
+		info     []byte
+		expected string
+	}{
+		{"Case1", salt, info, "c69fe91b7aaee2dd5718d72dcaee0cce93f1b8e41f792da51261b6a517e68b36ed2c595572b01dfa359b"},
+		{"Case3", nil, nil, "c8c91a38ae2fb3b023a7c38ce9f0748f28230d59b6b950ba3ba949bf0d713a5774815778801741cb2034"},
+	}
+	for _, c := range cases {
+		key, err := encryption.HKDFSM3(ikm, c.salt, c.info, 42)
+		if err != nil {
+			t.Fatalf("%s: HKDFSM3 failed: %v", c.name, err)
+		}
+		if hex.EncodeToString(key) != c.expected {
+			t.Errorf("%s: HKDFSM3 mismatch. Expected: %s, Got: %x", c.name, c.expected, key)
+		}
+
+		prk, err := encryption.HKDFSM3Extract(ikm, c.salt)
+		if err != nil {
+			t.Fatalf("%s: HKDFSM3Extract failed: %v", c.name, err)
+		}
+		expanded, err := encryption.HKDFSM3Expand(prk, c.info, 42)
+		if err != nil {
+			t.Fatalf("%s: HKDFSM3Expand failed: %v", c.name, err)
+		}
+		if !bytes.Equal(expanded, key) {
+			t.Errorf("%s: Extract+Expand should equal HKDFSM3", c.name)
+		}
+	}
+
+	if _, err := encryption.HKDFSM3(ikm, salt, info, 255*32+1); !errors.Is(err, encryption.ErrInvalidKeyLength) {
+		t.Errorf("Expected ErrInvalidKeyLength, got: %v", err)
+	}
+}
+
+func TestNewSM4FromPassphrase(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	sm4Enc, err := encryption.NewSM4FromPassphrase([]byte("correct horse battery staple"), salt, 1000)
+	if err != nil {
+		t.Fatalf("NewSM4FromPassphrase failed: %v", err)
+	}
+	ciphertext, err := sm4Enc.Encrypt("secret backup")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	// The same passphrase and salt derive the same key
+	sm4Dec, err := encryption.NewSM4FromPassphrase([]byte("correct horse battery staple"), salt, 1000)
+	if err != nil {
+		t.Fatalf("NewSM4FromPassphrase failed: %v", err)
+	}
+	plaintext, err := sm4Dec.Decrypt(append([]byte(nil), ciphertext...))
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if string(plaintext) != "secret backup" {
+		t.Errorf("Decrypted text mismatch: %s", plaintext)
+	}
+
+	// A different salt derives a different key
+	other, err := encryption.NewSM4FromPassphrase([]byte("correct horse battery staple"), []byte("fedcba9876543210"), 1000)
+	if err != nil {
+		t.Fatalf("NewSM4FromPassphrase failed: %v", err)
+	}
+	otherCiphertext, err := other.Encrypt("secret backup")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if bytes.Equal(otherCiphertext, ciphertext) {
+		t.Error("Different salts should derive different keys")
+	}
+
+	if _, err := encryption.NewSM4FromPassphrase([]byte("passphrase"), []byte("short"), 0); !errors.Is(err, encryption.ErrInvalidSalt) {
+		t.Errorf("Expected ErrInvalidSalt, got: %v", err)
+	}
+}
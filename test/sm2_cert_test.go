@@ -0,0 +1,236 @@
+package test
+
+import (
+	"crypto/x509/pkix"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tjfoc/gmsm/x509"
+
+	"xyz/test/helloworld/encryption"
+)
+
+type testCA struct {
+	key  *encryption.SM2
+	cert *x509.Certificate
+}
+
+func newTestRootCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	der, err := key.CreateCACertificate(&encryption.CertificateOptions{
+		Subject: pkix.Name{CommonName: "SM2 Test Root CA", Organization: []string{"helloworld"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateCACertificate failed: %v", err)
+	}
+	cert, err := encryption.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate failed: %v", err)
+	}
+	return &testCA{key: key, cert: cert}
+}
+
+// issue creates a key pair, a CSR for it and a certificate signed by ca.
+func (ca *testCA) issue(t *testing.T, opts *encryption.CertificateOptions) (*encryption.SM2, *x509.Certificate) {
+	t.Helper()
+	key, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	csr, err := key.CreateCertificateRequest(opts)
+	if err != nil {
+		t.Fatalf("CreateCertificateRequest failed: %v", err)
+	}
+	der, err := ca.key.IssueCertificate(csr, ca.cert, opts)
+	if err != nil {
+		t.Fatalf("IssueCertificate failed: %v", err)
+	}
+	cert, err := encryption.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate failed: %v", err)
+	}
+	return key, cert
+}
+
+func TestCertificateIssuance(t *testing.T) {
+	root := newTestRootCA(t)
+	if !root.cert.IsCA || root.cert.SignatureAlgorithm != x509.SM2WithSM3 {
+		t.Errorf("Root should be an SM2-with-SM3 CA, got IsCA=%v alg=%v", root.cert.IsCA, root.cert.SignatureAlgorithm)
+	}
+
+	leafKey, leaf := root.issue(t, &encryption.CertificateOptions{
+		Subject:     pkix.Name{CommonName: "api.example.com"},
+		DNSNames:    []string{"api.example.com"},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if leaf.Subject.CommonName != "api.example.com" || len(leaf.DNSNames) != 1 || len(leaf.IPAddresses) != 1 {
+		t.Errorf("Leaf subject or SANs not copied from CSR: %v %v %v", leaf.Subject, leaf.DNSNames, leaf.IPAddresses)
+	}
+	if leaf.IsCA {
+		t.Error("Leaf certificate should not be a CA")
+	}
+	if err := leaf.CheckSignatureFrom(root.cert); err != nil {
+		t.Errorf("Leaf signature check failed: %v", err)
+	}
+
+	// The certificate key loads straight into an SM2 instance
+	verifier, err := encryption.NewSM2FromCertificate(leaf)
+	if err != nil {
+		t.Fatalf("NewSM2FromCertificate failed: %v", err)
+	}
+	if verifier.PublicKeyHex() != leafKey.PublicKeyHex() {
+		t.Errorf("Public key mismatch. Expected: %s, Got: %s", leafKey.PublicKeyHex(), verifier.PublicKeyHex())
+	}
+	signature, err := leafKey.Sign([]byte("message"), encryption.SignatureASN1)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if err := verifier.Verify([]byte("message"), signature, encryption.SignatureASN1); err != nil {
+		t.Errorf("Verify with certificate key failed: %v", err)
+	}
+}
+
+func TestVerifyCertificateChain(t *testing.T) {
+	root := newTestRootCA(t)
+	intermediateKey, intermediateCert := root.issue(t, &encryption.CertificateOptions{
+		Subject: pkix.Name{CommonName: "SM2 Test Intermediate CA"},
+		IsCA:    true,
+	})
+	intermediate := &testCA{key: intermediateKey, cert: intermediateCert}
+	_, leaf := intermediate.issue(t, &encryption.CertificateOptions{
+		Subject: pkix.Name{CommonName: "leaf"},
+	})
+
+	chains, err := encryption.VerifyCertificateChain(leaf, []*x509.Certificate{intermediateCert}, []*x509.Certificate{root.cert})
+	if err != nil {
+		t.Fatalf("VerifyCertificateChain failed: %v", err)
+	}
+	if len(chains) != 1 || len(chains[0]) != 3 {
+		t.Errorf("Expected one chain of length 3, got: %v", chains)
+	}
+
+	// Missing intermediate
+	if _, err := encryption.VerifyCertificateChain(leaf, nil, []*x509.Certificate{root.cert}); !errors.Is(err, encryption.ErrCertificateVerify) {
+		t.Errorf("Expected ErrCertificateVerify without intermediate, got: %v", err)
+	}
+	// Untrusted root
+	otherRoot := newTestRootCA(t)
+	if _, err := encryption.VerifyCertificateChain(leaf, []*x509.Certificate{intermediateCert}, []*x509.Certificate{otherRoot.cert}); !errors.Is(err, encryption.ErrCertificateVerify) {
+		t.Errorf("Expected ErrCertificateVerify for untrusted root, got: %v", err)
+	}
+}
+
+func TestVerifyCertificateChainExpired(t *testing.T) {
+	root := newTestRootCA(t)
+	_, leaf := root.issue(t, &encryption.CertificateOptions{
+		Subject:   pkix.Name{CommonName: "expired"},
+		NotBefore: time.Now().Add(-48 * time.Hour),
+		NotAfter:  time.Now().Add(-24 * time.Hour),
+	})
+	if _, err := encryption.VerifyCertificateChain(leaf, nil, []*x509.Certificate{root.cert}); !errors.Is(err, encryption.ErrCertificateVerify) {
+		t.Errorf("Expected ErrCertificateVerify for expired certificate, got: %v", err)
+	}
+}
+
+func TestCertificatePEMRoundTrip(t *testing.T) {
+	root := newTestRootCA(t)
+	key, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	csr, err := key.CreateCertificateRequest(&encryption.CertificateOptions{Subject: pkix.Name{CommonName: "pem"}})
+	if err != nil {
+		t.Fatalf("CreateCertificateRequest failed: %v", err)
+	}
+	if _, err := encryption.ParseCertificateRequest(csr); err != nil {
+		t.Errorf("ParseCertificateRequest failed: %v", err)
+	}
+	der, err := root.key.IssueCertificate(csr, root.cert, nil)
+	if err != nil {
+		t.Fatalf("IssueCertificate failed: %v", err)
+	}
+
+	bundle := append(encryption.EncodeCertificatePEM(der), encryption.EncodeCertificatePEM(root.cert.Raw)...)
+	certs, err := encryption.ParseCertificatesPEM(bundle)
+	if err != nil {
+		t.Fatalf("ParseCertificatesPEM failed: %v", err)
+	}
+	if len(certs) != 2 || certs[0].Subject.CommonName != "pem" || !certs[1].Equal(root.cert) {
+		t.Errorf("Unexpected certificates parsed from bundle")
+	}
+
+	sm2Enc, err := encryption.NewSM2FromCertificatePEM(bundle)
+	if err != nil {
+		t.Fatalf("NewSM2FromCertificatePEM failed: %v", err)
+	}
+	if sm2Enc.PublicKeyHex() != key.PublicKeyHex() {
+		t.Error("NewSM2FromCertificatePEM should use the first certificate")
+	}
+}
+
+func TestOpenSSLCertificateFixture(t *testing.T) {
+	sm2Enc, err := encryption.NewSM2FromCertificatePEM(readFixture(t, "sm2_cert.pem"))
+	if err != nil {
+		t.Fatalf("NewSM2FromCertificatePEM failed: %v", err)
+	}
+	if sm2Enc.PublicKeyHex() != fixturePublicKeyHex {
+		t.Errorf("Public key mismatch. Expected: %s, Got: %s", fixturePublicKeyHex, sm2Enc.PublicKeyHex())
+	}
+	if sm2Enc.HasPrivateKey() {
+		t.Error("Certificate should only provide a public key")
+	}
+
+	certs, err := encryption.ParseCertificatesPEM(readFixture(t, "sm2_cert.pem"))
+	if err != nil {
+		t.Fatalf("ParseCertificatesPEM failed: %v", err)
+	}
+	if _, err := encryption.VerifyCertificateChain(certs[0], nil, certs); err != nil {
+		t.Errorf("Self-signed OpenSSL certificate should verify: %v", err)
+	}
+}
+
+func TestCertificateErrorHandling(t *testing.T) {
+	root := newTestRootCA(t)
+	key, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	csr, err := key.CreateCertificateRequest(nil)
+	if err != nil {
+		t.Fatalf("CreateCertificateRequest failed: %v", err)
+	}
+
+	// Tampered CSR signature
+	tampered := append([]byte(nil), csr...)
+	tampered[len(tampered)-1] ^= 0x01
+	if _, err := root.key.IssueCertificate(tampered, root.cert, nil); !errors.Is(err, encryption.ErrInvalidCertificate) {
+		t.Errorf("Expected ErrInvalidCertificate for tampered CSR, got: %v", err)
+	}
+	// CA certificate belonging to another key
+	if _, err := key.IssueCertificate(csr, root.cert, nil); !errors.Is(err, encryption.ErrKeyMismatch) {
+		t.Errorf("Expected ErrKeyMismatch, got: %v", err)
+	}
+	// Issuer that is not a CA
+	leafKey, leaf := root.issue(t, nil)
+	if _, err := leafKey.IssueCertificate(csr, leaf, nil); !errors.Is(err, encryption.ErrInvalidCertificate) {
+		t.Errorf("Expected ErrInvalidCertificate for non-CA issuer, got: %v", err)
+	}
+
+	publicOnly, err := encryption.NewSM2Encryptor(key.PublicKeyHex())
+	if err != nil {
+		t.Fatalf("Failed to create SM2 encryptor: %v", err)
+	}
+	if _, err := publicOnly.CreateCertificateRequest(nil); !errors.Is(err, encryption.ErrPrivateKeyRequired) {
+		t.Errorf("Expected ErrPrivateKeyRequired, got: %v", err)
+	}
+	if _, err := encryption.ParseCertificatesPEM(readFixture(t, "sm2_spki.pem")); !errors.Is(err, encryption.ErrInvalidCertificate) {
+		t.Errorf("Expected ErrInvalidCertificate for non-certificate PEM, got: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package test
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"testing"
+
+	"xyz/test/helloworld/encryption"
+)
+
+var allCipherFormats = []encryption.CipherFormat{
+	encryption.CipherC1C3C2,
+	encryption.CipherC1C2C3,
+	encryption.CipherC1C3C2NoPrefix,
+	encryption.CipherC1C2C3NoPrefix,
+	encryption.CipherASN1,
+}
+
+func TestSM2CipherFormats(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	plaintext := "Hello, SM2 cipher formats!"
+
+	for _, format := range allCipherFormats {
+		ciphertext, err := sm2Enc.Encrypt(plaintext, format)
+		if err != nil {
+			t.Fatalf("Encryption failed (format %d): %v", format, err)
+		}
+
+		decrypted, err := sm2Enc.Decrypt(ciphertext, format)
+		if err != nil {
+			t.Fatalf("Decryption failed (format %d): %v", format, err)
+		}
+		if string(decrypted) != plaintext {
+			t.Errorf("Decrypted text doesn't match original (format %d). Got: %s", format, string(decrypted))
+		}
+
+		// Raw layouts have a fixed overhead of C1 (64) + C3 (32) plus an optional prefix byte
+		switch format {
+		case encryption.CipherC1C3C2, encryption.CipherC1C2C3:
+			if len(ciphertext) != 97+len(plaintext) || ciphertext[0] != 0x04 {
+				t.Errorf("Unexpected prefixed ciphertext layout (format %d)", format)
+			}
+		case encryption.CipherC1C3C2NoPrefix, encryption.CipherC1C2C3NoPrefix:
+			if len(ciphertext) != 96+len(plaintext) {
+				t.Errorf("Unexpected unprefixed ciphertext length (format %d)", format)
+			}
+		}
+	}
+}
+
+func TestConvertCiphertext(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	plaintext := "Hello, SM2 cipher conversion!"
+	original, err := sm2Enc.Encrypt(plaintext, encryption.CipherC1C3C2)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+
+	for _, format := range allCipherFormats {
+		converted, err := encryption.ConvertCiphertext(original, encryption.CipherC1C3C2, format)
+		if err != nil {
+			t.Fatalf("Conversion to format %d failed: %v", format, err)
+		}
+		decrypted, err := sm2Enc.Decrypt(converted, format)
+		if err != nil {
+			t.Fatalf("Decryption of converted ciphertext failed (format %d): %v", format, err)
+		}
+		if string(decrypted) != plaintext {
+			t.Errorf("Converted ciphertext decrypted incorrectly (format %d)", format)
+		}
+
+		back, err := encryption.ConvertCiphertext(converted, format, encryption.CipherC1C3C2)
+		if err != nil {
+			t.Fatalf("Conversion back from format %d failed: %v", format, err)
+		}
+		if !bytes.Equal(back, original) {
+			t.Errorf("Round-trip conversion changed ciphertext (format %d)", format)
+		}
+	}
+}
+
+// TestSM2DecryptOpenSSLCiphertext decrypts an ASN.1 ciphertext produced by
+//
+//	openssl pkeyutl -encrypt -pubin -inkey sm2_spki.pem
+func TestSM2DecryptOpenSSLCiphertext(t *testing.T) {
+	ciphertextHex := "307d0221009a4765dcd5a6d5b21204be8be51ce61ae65c07ec9519a0915bf29fc6ebb2dd53" +
+		"022100b67a137a79c5c49d8b4b686dc3cd9dd2f38ec07bdf334c039458554c0d7edc82" +
+		"0420fb5c6e2d41b92d384650a437aa244fa7946da60e139c0f363742a5321db6c33a" +
+		"0413d7ded34083944fffdfdfa7d971cae0e081db74"
+
+	sm2Enc, err := encryption.NewSM2FromPEM(readFixture(t, "sm2_pkcs8.pem"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load private key: %v", err)
+	}
+	decrypted, err := sm2Enc.DecryptHex(ciphertextHex, encryption.CipherASN1)
+	if err != nil {
+		t.Fatalf("Decryption of OpenSSL ciphertext failed: %v", err)
+	}
+	if string(decrypted) != "Hello, OpenSSL SM2!" {
+		t.Errorf("Unexpected plaintext: %s", string(decrypted))
+	}
+}
+
+// TestSM2CiphertextErrorHandling tests that malformed ciphertexts are rejected instead of panicking
+func TestSM2CiphertextErrorHandling(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		ciphertext []byte
+		format     encryption.CipherFormat
+	}{
+		{"Empty", nil, encryption.CipherC1C3C2},
+		{"PrefixOnly", []byte{0x04}, encryption.CipherC1C3C2},
+		{"MissingPrefix", make([]byte, 100), encryption.CipherC1C2C3},
+		{"TooShort", make([]byte, 95), encryption.CipherC1C3C2NoPrefix},
+		{"BadASN1", []byte{0x30, 0x01}, encryption.CipherASN1},
+		{"UnknownFormat", make([]byte, 100), encryption.CipherFormat(99)},
+		{"C1NotOnCurve", append([]byte{0x04}, make([]byte, 100)...), encryption.CipherC1C3C2},
+		{"ASN1C1NotOnCurve", offCurveASN1Ciphertext(t), encryption.CipherASN1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := sm2Enc.Decrypt(c.ciphertext, c.format); !errors.Is(err, encryption.ErrInvalidCiphertext) {
+				t.Errorf("Expected ErrInvalidCiphertext, got %v", err)
+			}
+		})
+	}
+
+	// Conversion refuses to emit a raw ciphertext with an off-curve C1
+	for _, to := range []encryption.CipherFormat{encryption.CipherC1C3C2, encryption.CipherC1C2C3NoPrefix} {
+		if _, err := encryption.ConvertCiphertext(offCurveASN1Ciphertext(t), encryption.CipherASN1, to); !errors.Is(err, encryption.ErrInvalidCiphertext) {
+			t.Errorf("Expected ErrInvalidCiphertext converting to format %d, got %v", to, err)
+		}
+	}
+}
+
+func TestSM2DecryptTamperedC2(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	ciphertext, err := sm2Enc.Encrypt("Hello, SM2!", encryption.CipherC1C3C2)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	// C2 follows the 0x04 prefix, C1 (64 bytes) and C3 (32 bytes)
+	ciphertext[1+64+32] ^= 0x01
+
+	plaintext, err := sm2Enc.Decrypt(ciphertext, encryption.CipherC1C3C2)
+	if err == nil {
+		t.Fatal("Expected error for tampered C2")
+	}
+	if plaintext != nil {
+		t.Errorf("Unauthenticated plaintext returned with error: %q", plaintext)
+	}
+}
+
+// offCurveASN1Ciphertext returns a well-formed SM2Cipher whose C1 = (1, 1) is not on the curve
+func offCurveASN1Ciphertext(t *testing.T) []byte {
+	t.Helper()
+	der, err := asn1.Marshal(struct {
+		X, Y       *big.Int
+		Hash, Text []byte
+	}{big.NewInt(1), big.NewInt(1), make([]byte, 32), []byte("ciphertext")})
+	if err != nil {
+		t.Fatalf("Failed to marshal SM2Cipher: %v", err)
+	}
+	return der
+}
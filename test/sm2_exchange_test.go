@@ -0,0 +1,184 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"xyz/test/helloworld/encryption"
+)
+
+// exchangeParties returns A and B key pairs together with each side's public-only view of the other.
+func exchangeParties(t *testing.T) (a, b, aPublic, bPublic *encryption.SM2) {
+	t.Helper()
+	var err error
+	if a, err = encryption.GenerateSM2KeyPair(); err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	if b, err = encryption.GenerateSM2KeyPair(); err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	if aPublic, err = encryption.NewSM2Encryptor(a.PublicKeyHex()); err != nil {
+		t.Fatalf("Failed to create SM2 encryptor: %v", err)
+	}
+	if bPublic, err = encryption.NewSM2Encryptor(b.PublicKeyHex()); err != nil {
+		t.Fatalf("Failed to create SM2 encryptor: %v", err)
+	}
+	return
+}
+
+func TestKeyExchange(t *testing.T) {
+	for _, keyLen := range []int{16, 32, 48, 100} {
+		a, b, aPublic, bPublic := exchangeParties(t)
+
+		initiator, err := a.NewKeyExchangeInitiator(bPublic, keyLen)
+		if err != nil {
+			t.Fatalf("NewKeyExchangeInitiator failed: %v", err)
+		}
+		responder, err := b.NewKeyExchangeResponder(aPublic, keyLen)
+		if err != nil {
+			t.Fatalf("NewKeyExchangeResponder failed: %v", err)
+		}
+
+		ra, err := initiator.Init()
+		if err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+		rb, sb, err := responder.Respond(ra)
+		if err != nil {
+			t.Fatalf("Respond failed: %v", err)
+		}
+		sa, err := initiator.Finish(rb, sb)
+		if err != nil {
+			t.Fatalf("Finish failed: %v", err)
+		}
+		if err := responder.Confirm(sa); err != nil {
+			t.Fatalf("Confirm failed: %v", err)
+		}
+
+		if len(initiator.Key()) != keyLen {
+			t.Errorf("Key length mismatch. Expected: %d, Got: %d", keyLen, len(initiator.Key()))
+		}
+		if !bytes.Equal(initiator.Key(), responder.Key()) {
+			t.Errorf("Shared keys differ for length %d", keyLen)
+		}
+	}
+}
+
+func TestKeyExchangeSessionKey(t *testing.T) {
+	a, b, aPublic, bPublic := exchangeParties(t)
+	a.SetUserID([]byte("alice@example.com"))
+	aPublic.SetUserID([]byte("alice@example.com"))
+
+	// 32 bytes: SM4 key || IV
+	initiator, _ := a.NewKeyExchangeInitiator(bPublic, 32)
+	responder, _ := b.NewKeyExchangeResponder(aPublic, 32)
+	ra, _ := initiator.Init()
+	rb, _, err := responder.Respond(ra)
+	if err != nil {
+		t.Fatalf("Respond failed: %v", err)
+	}
+	// Confirmation is optional
+	if _, err := initiator.Finish(rb, nil); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+
+	keyA, keyB := initiator.Key(), responder.Key()
+	sm4A, err := encryption.NewSM4(keyA[:16], keyA[16:])
+	if err != nil {
+		t.Fatalf("Failed to create SM4: %v", err)
+	}
+	sm4B, err := encryption.NewSM4(keyB[:16], keyB[16:])
+	if err != nil {
+		t.Fatalf("Failed to create SM4: %v", err)
+	}
+	ciphertext, err := sm4A.Encrypt("session message")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	plaintext, err := sm4B.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if string(plaintext) != "session message" {
+		t.Errorf("Decrypted message mismatch: %s", plaintext)
+	}
+}
+
+func TestKeyExchangeConfirmationFailure(t *testing.T) {
+	a, b, aPublic, bPublic := exchangeParties(t)
+
+	initiator, _ := a.NewKeyExchangeInitiator(bPublic, 16)
+	responder, _ := b.NewKeyExchangeResponder(aPublic, 16)
+	ra, _ := initiator.Init()
+	rb, sb, err := responder.Respond(ra)
+	if err != nil {
+		t.Fatalf("Respond failed: %v", err)
+	}
+
+	tampered := append([]byte(nil), sb...)
+	tampered[0] ^= 0xff
+	if _, err := initiator.Finish(rb, tampered); !errors.Is(err, encryption.ErrKeyConfirmation) {
+		t.Errorf("Expected ErrKeyConfirmation for tampered SB, got: %v", err)
+	}
+	if initiator.Key() != nil {
+		t.Error("Key should not be available after failed confirmation")
+	}
+
+	sa, err := initiator.Finish(rb, sb)
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	sa[len(sa)-1] ^= 0x01
+	if err := responder.Confirm(sa); !errors.Is(err, encryption.ErrKeyConfirmation) {
+		t.Errorf("Expected ErrKeyConfirmation for tampered SA, got: %v", err)
+	}
+}
+
+func TestKeyExchangeUserIDMismatch(t *testing.T) {
+	a, b, aPublic, bPublic := exchangeParties(t)
+	// B believes A uses a different identity, so ZA differs on both sides
+	a.SetUserID([]byte("alice"))
+
+	initiator, _ := a.NewKeyExchangeInitiator(bPublic, 16)
+	responder, _ := b.NewKeyExchangeResponder(aPublic, 16)
+	ra, _ := initiator.Init()
+	rb, sb, err := responder.Respond(ra)
+	if err != nil {
+		t.Fatalf("Respond failed: %v", err)
+	}
+	if _, err := initiator.Finish(rb, sb); !errors.Is(err, encryption.ErrKeyConfirmation) {
+		t.Errorf("Expected ErrKeyConfirmation for mismatched user IDs, got: %v", err)
+	}
+}
+
+func TestKeyExchangeErrorHandling(t *testing.T) {
+	a, b, aPublic, bPublic := exchangeParties(t)
+
+	if _, err := aPublic.NewKeyExchangeInitiator(bPublic, 16); !errors.Is(err, encryption.ErrPrivateKeyRequired) {
+		t.Errorf("Expected ErrPrivateKeyRequired, got: %v", err)
+	}
+	if _, err := a.NewKeyExchangeInitiator(bPublic, 0); !errors.Is(err, encryption.ErrKeyExchange) {
+		t.Errorf("Expected ErrKeyExchange for zero key length, got: %v", err)
+	}
+
+	initiator, _ := a.NewKeyExchangeInitiator(bPublic, 16)
+	responder, _ := b.NewKeyExchangeResponder(aPublic, 16)
+	if _, err := responder.Init(); !errors.Is(err, encryption.ErrKeyExchange) {
+		t.Errorf("Expected ErrKeyExchange when responder calls Init, got: %v", err)
+	}
+	if _, _, err := initiator.Respond(nil); !errors.Is(err, encryption.ErrKeyExchange) {
+		t.Errorf("Expected ErrKeyExchange when initiator calls Respond, got: %v", err)
+	}
+	if err := responder.Confirm(nil); !errors.Is(err, encryption.ErrKeyExchange) {
+		t.Errorf("Expected ErrKeyExchange for Confirm before Respond, got: %v", err)
+	}
+
+	// Ephemeral point not on the curve
+	ra, _ := initiator.Init()
+	invalid := append([]byte(nil), ra...)
+	invalid[len(invalid)-1] ^= 0x01
+	if _, _, err := responder.Respond(invalid); !errors.Is(err, encryption.ErrKeyExchange) {
+		t.Errorf("Expected ErrKeyExchange for invalid ephemeral key, got: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"xyz/test/helloworld/encryption"
+)
+
+// Key material in testdata was generated with OpenSSL 3:
+//
+//	openssl genpkey -algorithm EC -pkeyopt ec_paramgen_curve:SM2 -out sm2_pkcs8.pem
+//	openssl pkey -in sm2_pkcs8.pem -pubout -out sm2_spki.pem
+//	openssl pkcs8 -topk8 -in sm2_pkcs8.pem -v2 aes-256-cbc -passout pass:123456 -out sm2_pkcs8_encrypted.pem
+//	openssl ec -in sm2_pkcs8.pem -out sm2_sec1.pem
+const (
+	fixturePublicKeyHex = "04dec7dc3120e71c9f185812bc655a35cd328556dfb69692a622f8498da8588907" +
+		"ddde2b3267f68481da03e2d74cd3c2aa33d8df0560c68cad1d10eecd37c72df8"
+	fixturePrivateKeyHex = "f87bf882933d5def89627ff61aca7a03f50e3f08ef3b9585c706325f3107f30a"
+	fixturePassword      = "123456"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("Failed to read fixture %s: %v", name, err)
+	}
+	return data
+}
+
+func TestNewSM2FromPEMFixtures(t *testing.T) {
+	cases := []struct {
+		name     string
+		file     string
+		password []byte
+	}{
+		{"PKCS8", "sm2_pkcs8.pem", nil},
+		{"EncryptedPKCS8", "sm2_pkcs8_encrypted.pem", []byte(fixturePassword)},
+		{"SEC1", "sm2_sec1.pem", nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sm2Enc, err := encryption.NewSM2FromPEM(readFixture(t, c.file), c.password)
+			if err != nil {
+				t.Fatalf("Failed to load %s: %v", c.file, err)
+			}
+			if sm2Enc.PrivateKeyHex() != fixturePrivateKeyHex {
+				t.Errorf("Private key mismatch. Expected: %s, Got: %s", fixturePrivateKeyHex, sm2Enc.PrivateKeyHex())
+			}
+			if sm2Enc.PublicKeyHex() != fixturePublicKeyHex {
+				t.Errorf("Public key mismatch. Expected: %s, Got: %s", fixturePublicKeyHex, sm2Enc.PublicKeyHex())
+			}
+		})
+	}
+}
+
+func TestDecodePublicKeyPEMFixture(t *testing.T) {
+	publicKey, err := encryption.DecodePublicKeyPEM(readFixture(t, "sm2_spki.pem"))
+	if err != nil {
+		t.Fatalf("Failed to decode public key PEM: %v", err)
+	}
+	expected, err := encryption.DecodePublicKey(fixturePublicKeyHex)
+	if err != nil {
+		t.Fatalf("Failed to decode public key hex: %v", err)
+	}
+	if publicKey.X.Cmp(expected.X) != 0 || publicKey.Y.Cmp(expected.Y) != 0 {
+		t.Error("Public key from PEM doesn't match fixture")
+	}
+}
+
+func TestSM2PEMRoundTrip(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	// Public key PEM/DER
+	publicKeyPEM, err := sm2Enc.PublicKeyPEM()
+	if err != nil {
+		t.Fatalf("Failed to export public key PEM: %v", err)
+	}
+	publicKey, err := encryption.DecodePublicKeyPEM(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to decode exported public key PEM: %v", err)
+	}
+	if hex.EncodeToString(encryption.EncodePublicKey(publicKey)) != sm2Enc.PublicKeyHex() {
+		t.Error("Public key PEM round trip mismatch")
+	}
+	publicKeyDER, err := sm2Enc.PublicKeyDER()
+	if err != nil {
+		t.Fatalf("Failed to export public key DER: %v", err)
+	}
+	if _, err := encryption.DecodePublicKeyDER(publicKeyDER); err != nil {
+		t.Errorf("Failed to decode exported public key DER: %v", err)
+	}
+
+	// Plain and encrypted private key PEM/DER
+	for _, password := range [][]byte{nil, []byte("secret")} {
+		privateKeyPEM, err := sm2Enc.PrivateKeyPEM(password)
+		if err != nil {
+			t.Fatalf("Failed to export private key PEM: %v", err)
+		}
+		restored, err := encryption.NewSM2FromPEM(privateKeyPEM, password)
+		if err != nil {
+			t.Fatalf("Failed to load exported private key PEM: %v", err)
+		}
+		if restored.PrivateKeyHex() != sm2Enc.PrivateKeyHex() || restored.PublicKeyHex() != sm2Enc.PublicKeyHex() {
+			t.Error("Private key PEM round trip mismatch")
+		}
+
+		privateKeyDER, err := sm2Enc.PrivateKeyDER(password)
+		if err != nil {
+			t.Fatalf("Failed to export private key DER: %v", err)
+		}
+		restored, err = encryption.NewSM2FromDER(privateKeyDER, password)
+		if err != nil {
+			t.Fatalf("Failed to load exported private key DER: %v", err)
+		}
+		if restored.PrivateKeyHex() != sm2Enc.PrivateKeyHex() {
+			t.Error("Private key DER round trip mismatch")
+		}
+	}
+}
+
+// TestSM2PEMErrorHandling tests error handling in PEM loaders
+func TestSM2PEMErrorHandling(t *testing.T) {
+	// Test with data that is not PEM
+	if _, err := encryption.NewSM2FromPEM([]byte("invalid"), nil); err == nil {
+		t.Error("Expected error for invalid PEM, but got nil")
+	}
+
+	// Test encrypted key without password
+	if _, err := encryption.NewSM2FromPEM(readFixture(t, "sm2_pkcs8_encrypted.pem"), nil); err == nil {
+		t.Error("Expected error for encrypted key without password, but got nil")
+	}
+
+	// Test encrypted key with wrong password
+	if _, err := encryption.NewSM2FromPEM(readFixture(t, "sm2_pkcs8_encrypted.pem"), []byte("wrong")); err == nil {
+		t.Error("Expected error for wrong password, but got nil")
+	}
+
+	// Test public key PEM passed as private key and vice versa
+	if _, err := encryption.NewSM2FromPEM(readFixture(t, "sm2_spki.pem"), nil); err == nil {
+		t.Error("Expected error for public key PEM in private key loader, but got nil")
+	}
+	if _, err := encryption.DecodePublicKeyPEM(readFixture(t, "sm2_pkcs8.pem")); err == nil {
+		t.Error("Expected error for private key PEM in public key loader, but got nil")
+	}
+}
+
+func TestNewSM2EncryptorFromPEM(t *testing.T) {
+	encryptor, err := encryption.NewSM2EncryptorFromPEM(readFixture(t, "sm2_spki.pem"))
+	if err != nil {
+		t.Fatalf("Failed to load public key PEM: %v", err)
+	}
+	decryptor, err := encryption.NewSM2FromPEM(readFixture(t, "sm2_pkcs8.pem"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load private key PEM: %v", err)
+	}
+
+	ciphertext, err := encryptor.Encrypt("Hello, SM2 PEM!", 0)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	decrypted, err := decryptor.Decrypt(ciphertext, 0)
+	if err != nil {
+		t.Fatalf("Decryption failed: %v", err)
+	}
+	if string(decrypted) != "Hello, SM2 PEM!" {
+		t.Errorf("Decrypted text doesn't match original. Got: %s", string(decrypted))
+	}
+}
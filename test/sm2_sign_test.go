@@ -0,0 +1,190 @@
+package test
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"testing"
+
+	"xyz/test/helloworld/encryption"
+)
+
+func TestSM2SignAndVerify(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	msg := []byte("Hello, SM2 signature!")
+
+	for _, format := range []encryption.SignatureFormat{encryption.SignatureASN1, encryption.SignatureRaw} {
+		// Test Sign and Verify
+		signature, err := sm2Enc.Sign(msg, format)
+		if err != nil {
+			t.Fatalf("Sign failed (format %d): %v", format, err)
+		}
+		if format == encryption.SignatureRaw && len(signature) != 64 {
+			t.Errorf("Raw signature should be 64 bytes, got %d", len(signature))
+		}
+		if err := sm2Enc.Verify(msg, signature, format); err != nil {
+			t.Errorf("Verify failed (format %d): %v", format, err)
+		}
+
+		// Test Sign2Hex and VerifyHex
+		hexSignature, err := sm2Enc.Sign2Hex(msg, format)
+		if err != nil {
+			t.Fatalf("Hex sign failed (format %d): %v", format, err)
+		}
+		if err := sm2Enc.VerifyHex(msg, hexSignature, format); err != nil {
+			t.Errorf("Hex verify failed (format %d): %v", format, err)
+		}
+
+		// Test Sign2Base64 and VerifyBase64
+		base64Signature, err := sm2Enc.Sign2Base64(msg, format)
+		if err != nil {
+			t.Fatalf("Base64 sign failed (format %d): %v", format, err)
+		}
+		if err := sm2Enc.VerifyBase64(msg, base64Signature, format); err != nil {
+			t.Errorf("Base64 verify failed (format %d): %v", format, err)
+		}
+
+		// Tampered message must not verify
+		if err := sm2Enc.Verify([]byte("tampered"), signature, format); !errors.Is(err, encryption.ErrInvalidSignature) {
+			t.Errorf("Expected ErrInvalidSignature for tampered message, got %v", err)
+		}
+	}
+}
+
+func TestSM2SignObject(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	type TestObject struct {
+		Name  string `json:"name"`
+		Value int    `json:"value"`
+	}
+
+	testObj := TestObject{Name: "test", Value: 123}
+
+	signature, err := sm2Enc.SignObject(testObj, encryption.SignatureASN1)
+	if err != nil {
+		t.Fatalf("Object sign failed: %v", err)
+	}
+	if err := sm2Enc.VerifyObject(testObj, signature, encryption.SignatureASN1); err != nil {
+		t.Errorf("Object verify failed: %v", err)
+	}
+
+	testObj.Value = 456
+	if err := sm2Enc.VerifyObject(testObj, signature, encryption.SignatureASN1); err == nil {
+		t.Error("Expected verify failure for modified object, but got nil")
+	}
+}
+
+func TestSM2SignUserID(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	msg := []byte("Hello, SM2 user ID!")
+
+	// Signature made with the default ID must not verify under another ID
+	signature, err := sm2Enc.Sign(msg, encryption.SignatureRaw)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	sm2Enc.SetUserID([]byte("ALICE123@YAHOO.COM"))
+	if err := sm2Enc.Verify(msg, signature, encryption.SignatureRaw); err == nil {
+		t.Error("Expected verify failure with different user ID, but got nil")
+	}
+
+	// Explicitly setting the default ID behaves like not setting one
+	sm2Enc.SetUserID([]byte(encryption.DefaultUserID))
+	if err := sm2Enc.Verify(msg, signature, encryption.SignatureRaw); err != nil {
+		t.Errorf("Verify with default user ID failed: %v", err)
+	}
+}
+
+func TestConvertSignature(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	msg := []byte("Hello, SM2 signature conversion!")
+	derSignature, err := sm2Enc.Sign(msg, encryption.SignatureASN1)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	rawSignature, err := encryption.ConvertSignature(derSignature, encryption.SignatureASN1, encryption.SignatureRaw)
+	if err != nil {
+		t.Fatalf("DER to raw conversion failed: %v", err)
+	}
+	if err := sm2Enc.Verify(msg, rawSignature, encryption.SignatureRaw); err != nil {
+		t.Errorf("Converted raw signature verify failed: %v", err)
+	}
+
+	back, err := encryption.ConvertSignature(rawSignature, encryption.SignatureRaw, encryption.SignatureASN1)
+	if err != nil {
+		t.Fatalf("Raw to DER conversion failed: %v", err)
+	}
+	if !bytes.Equal(back, derSignature) {
+		t.Error("Round-tripped DER signature doesn't match original")
+	}
+
+	// r and s outside [1, n-1] are rejected instead of overflowing the 32-byte raw fields
+	n, _ := new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123", 16)
+	one := big.NewInt(1)
+	for _, rs := range [][2]*big.Int{
+		{new(big.Int).Lsh(one, 300), one},
+		{one, new(big.Int).Lsh(one, 256)},
+		{n, one},
+		{big.NewInt(0), one},
+		{big.NewInt(-1), one},
+	} {
+		der, err := asn1.Marshal(struct{ R, S *big.Int }{rs[0], rs[1]})
+		if err != nil {
+			t.Fatalf("Failed to marshal signature: %v", err)
+		}
+		if _, err := encryption.ConvertSignature(der, encryption.SignatureASN1, encryption.SignatureRaw); !errors.Is(err, encryption.ErrInvalidSignature) {
+			t.Errorf("r=%v s=%v: Expected ErrInvalidSignature, got %v", rs[0], rs[1], err)
+		}
+	}
+	raw := make([]byte, 64)
+	raw[63] = 1
+	if _, err := encryption.ConvertSignature(raw, encryption.SignatureRaw, encryption.SignatureASN1); !errors.Is(err, encryption.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for zero r, got %v", err)
+	}
+}
+
+// TestSM2VerifyErrorHandling tests error handling in SM2 verify functions
+func TestSM2VerifyErrorHandling(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	msg := []byte("test")
+
+	// Test VerifyHex with invalid hex string
+	if err := sm2Enc.VerifyHex(msg, "invalid", encryption.SignatureASN1); err == nil {
+		t.Error("Expected error for invalid hex string in VerifyHex, but got nil")
+	}
+
+	// Test VerifyBase64 with invalid base64 string
+	if err := sm2Enc.VerifyBase64(msg, "invalid!", encryption.SignatureASN1); err == nil {
+		t.Error("Expected error for invalid base64 string in VerifyBase64, but got nil")
+	}
+
+	// Test malformed signatures
+	if err := sm2Enc.Verify(msg, []byte{0x30, 0x00}, encryption.SignatureASN1); !errors.Is(err, encryption.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for malformed DER, got %v", err)
+	}
+	if err := sm2Enc.Verify(msg, make([]byte, 63), encryption.SignatureRaw); !errors.Is(err, encryption.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for short raw signature, got %v", err)
+	}
+}
@@ -1,48 +1,22 @@
 package test
 
 import (
-	"crypto/rand"
+	"bytes"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"testing"
 
 	"xyz/test/helloworld/encryption"
-
-	"github.com/tjfoc/gmsm/sm2"
 )
 
 // generateSM2KeyPair generates a valid SM2 key pair in the format expected by the encryption package
 func generateSM2KeyPair() (publicKeyHex, privateKeyHex string, err error) {
-	// Generate a valid SM2 key pair
-	privateKey, err := sm2.GenerateKey(rand.Reader)
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
 	if err != nil {
 		return "", "", err
 	}
-
-	// Create uncompressed public key format (0x04 || x || y)
-	publicKey := &privateKey.PublicKey
-	curve := publicKey.Curve
-	byteLen := (curve.Params().BitSize + 7) / 8
-
-	// Create uncompressed format: 0x04 || x || y
-	xBytes := publicKey.X.Bytes()
-	yBytes := publicKey.Y.Bytes()
-
-	// Pad x and y to full byte length
-	xPadded := make([]byte, byteLen)
-	yPadded := make([]byte, byteLen)
-	copy(xPadded[byteLen-len(xBytes):], xBytes)
-	copy(yPadded[byteLen-len(yBytes):], yBytes)
-
-	// Create uncompressed public key bytes
-	uncompressed := make([]byte, 1+2*byteLen)
-	uncompressed[0] = 0x04
-	copy(uncompressed[1:byteLen+1], xPadded)
-	copy(uncompressed[byteLen+1:], yPadded)
-
-	publicKeyHex = hex.EncodeToString(uncompressed)
-	privateKeyHex = hex.EncodeToString(privateKey.D.Bytes())
-
-	return publicKeyHex, privateKeyHex, nil
+	return sm2Enc.PublicKeyHex(), sm2Enc.PrivateKeyHex(), nil
 }
 
 func TestSM2Encryption(t *testing.T) {
@@ -270,10 +244,352 @@ func TestDecodePrivateKeyErrorHandling(t *testing.T) {
 	}
 
 	_, err = encryption.DecodePrivateKey(privateKeyHex, publicKeyHex)
-	// Note: This test may not always produce an error depending on the implementation
-	// We're just checking that the function doesn't panic
+	if !errors.Is(err, encryption.ErrKeyMismatch) {
+		t.Errorf("Expected ErrKeyMismatch for mismatched keys, got %v", err)
+	}
+}
+
+// TestDecodePublicKeyValidation tests that malformed public keys are rejected instead of panicking
+func TestDecodePublicKeyValidation(t *testing.T) {
+	publicKeyHex, _, err := generateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	// Flip the last byte of Y so the point falls off the curve
+	offCurve := publicKeyHex[:len(publicKeyHex)-2] + "00"
+	if offCurve == publicKeyHex {
+		offCurve = publicKeyHex[:len(publicKeyHex)-2] + "01"
+	}
+
+	cases := []struct {
+		name      string
+		key       string
+		wantError error
+	}{
+		{"Empty", "", encryption.ErrInvalidPublicKey},
+		{"InvalidHex", "zz", encryption.ErrInvalidPublicKey},
+		{"PrefixOnly", "04", encryption.ErrInvalidPublicKey},
+		{"Short", publicKeyHex[:64], encryption.ErrInvalidPublicKey},
+		{"Long", publicKeyHex + "00", encryption.ErrInvalidPublicKey},
+		{"BadPrefix", "05" + publicKeyHex[2:], encryption.ErrInvalidPublicKey},
+		{"Hybrid", "06" + publicKeyHex[2:], encryption.ErrUnsupportedPublicKey},
+		{"OffCurve", offCurve, encryption.ErrInvalidPublicKey},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := encryption.DecodePublicKey(c.key)
+			if !errors.Is(err, c.wantError) {
+				t.Errorf("Expected %v, got %v", c.wantError, err)
+			}
+		})
+	}
+}
+
+// TestDecodePrivateKeyValidation tests range checks on the private scalar
+func TestDecodePrivateKeyValidation(t *testing.T) {
+	publicKeyHex, _, err := generateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	// n is the order of the SM2 base point; D must be in [1, n-2]
+	n := "fffffffeffffffffffffffffffffffff7203df6b21c6052b53bbf40939d54123"
+	for _, d := range []string{"", "00", n, "ff" + n} {
+		_, err := encryption.DecodePrivateKey(d, publicKeyHex)
+		if !errors.Is(err, encryption.ErrInvalidPrivateKey) {
+			t.Errorf("Expected ErrInvalidPrivateKey for D=%q, got %v", d, err)
+		}
+	}
+
+	// NewSM2 propagates the same typed errors
+	_, err = encryption.NewSM2("04", "01")
+	if !errors.Is(err, encryption.ErrInvalidPublicKey) {
+		t.Errorf("Expected ErrInvalidPublicKey from NewSM2, got %v", err)
+	}
+}
+
+func TestGenerateSM2KeyPair(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	// Public key must be uncompressed 04||X||Y with 32-byte coordinates
+	publicKeyHex := sm2Enc.PublicKeyHex()
+	if len(publicKeyHex) != 130 || publicKeyHex[:2] != "04" {
+		t.Errorf("Unexpected public key layout: %s", publicKeyHex)
+	}
+
+	// Private key must be zero-padded to 32 bytes
+	privateKeyHex := sm2Enc.PrivateKeyHex()
+	if len(privateKeyHex) != 64 {
+		t.Errorf("Private key hex should be 64 characters, got %d", len(privateKeyHex))
+	}
+
+	// Base64 exports must carry the same bytes as the hex exports
+	publicKeyBytes, err := base64.StdEncoding.DecodeString(sm2Enc.PublicKeyBase64())
+	if err != nil {
+		t.Fatalf("Failed to decode public key base64: %v", err)
+	}
+	if hex.EncodeToString(publicKeyBytes) != publicKeyHex {
+		t.Error("Public key base64 export doesn't match hex export")
+	}
+	privateKeyBytes, err := base64.StdEncoding.DecodeString(sm2Enc.PrivateKeyBase64())
+	if err != nil {
+		t.Fatalf("Failed to decode private key base64: %v", err)
+	}
+	if hex.EncodeToString(privateKeyBytes) != privateKeyHex {
+		t.Error("Private key base64 export doesn't match hex export")
+	}
+
+	// Exported keys must round-trip through NewSM2
+	restored, err := encryption.NewSM2(publicKeyHex, privateKeyHex)
+	if err != nil {
+		t.Fatalf("Failed to restore SM2 instance from exported keys: %v", err)
+	}
+	ciphertext, err := sm2Enc.Encrypt("Hello, SM2 key generation!", 0)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	decrypted, err := restored.Decrypt(ciphertext, 0)
+	if err != nil {
+		t.Fatalf("Decryption with restored keys failed: %v", err)
+	}
+	if string(decrypted) != "Hello, SM2 key generation!" {
+		t.Errorf("Decrypted text doesn't match original. Got: %s", string(decrypted))
+	}
+}
+
+func TestEncodeKeysZeroPadding(t *testing.T) {
+	// D = 1 gives the generator point; both D and the encoded key must keep full width
+	privateKeyHex := "0000000000000000000000000000000000000000000000000000000000000001"
+	publicKeyHex := "0432c4ae2c1f1981195f9904466a39c9948fe30bbff2660be1715a4589334c74c7bc3736a2f4f6779c59bdcee36b692153d0a9877cc62a474002df32e52139f0a0"
+
+	sm2Enc, err := encryption.NewSM2(publicKeyHex, privateKeyHex)
+	if err != nil {
+		t.Fatalf("Failed to create SM2 instance: %v", err)
+	}
+	if sm2Enc.PrivateKeyHex() != privateKeyHex {
+		t.Errorf("Private key not zero-padded. Expected: %s, Got: %s", privateKeyHex, sm2Enc.PrivateKeyHex())
+	}
+	if sm2Enc.PublicKeyHex() != publicKeyHex {
+		t.Errorf("Public key export mismatch. Expected: %s, Got: %s", publicKeyHex, sm2Enc.PublicKeyHex())
+	}
+}
+
+func TestPublicKeyFormats(t *testing.T) {
+	sm2Enc, err := encryption.GenerateSM2KeyPair()
 	if err != nil {
-		// This is actually expected behavior - the function should return an error
-		// for mismatched keys, so we're good
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		format encryption.PublicKeyFormat
+		length int
+	}{
+		{"Uncompressed", encryption.PublicKeyUncompressed, 65},
+		{"Compressed", encryption.PublicKeyCompressed, 33},
+		{"Raw", encryption.PublicKeyRaw, 64},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			publicKeyHex, err := sm2Enc.PublicKeyHexWithFormat(c.format)
+			if err != nil {
+				t.Fatalf("Failed to export public key: %v", err)
+			}
+			if len(publicKeyHex) != 2*c.length {
+				t.Errorf("Unexpected exported length. Expected: %d bytes, Got: %d hex chars", c.length, len(publicKeyHex))
+			}
+
+			// Every format must decode back to the same point
+			publicKey, err := encryption.DecodePublicKey(publicKeyHex)
+			if err != nil {
+				t.Fatalf("Failed to decode exported public key: %v", err)
+			}
+			if hex.EncodeToString(encryption.EncodePublicKey(publicKey)) != sm2Enc.PublicKeyHex() {
+				t.Error("Decoded public key doesn't match original")
+			}
+
+			// The decoded key is usable with the original private key
+			if _, err := encryption.NewSM2(publicKeyHex, sm2Enc.PrivateKeyHex()); err != nil {
+				t.Errorf("NewSM2 rejected %s public key: %v", c.name, err)
+			}
+
+			base64Key, err := sm2Enc.PublicKeyBase64WithFormat(c.format)
+			if err != nil {
+				t.Fatalf("Failed to export public key base64: %v", err)
+			}
+			raw, _ := base64.StdEncoding.DecodeString(base64Key)
+			if hex.EncodeToString(raw) != publicKeyHex {
+				t.Error("Base64 export doesn't match hex export")
+			}
+		})
+	}
+
+	if _, err := sm2Enc.PublicKeyHexWithFormat(encryption.PublicKeyFormat(99)); err == nil {
+		t.Error("Expected error for unknown public key format, but got nil")
+	}
+}
+
+func TestDecodeCompressedPublicKeyVector(t *testing.T) {
+	// The SM2 base point G in compressed form (Gy is even)
+	compressed := "0232c4ae2c1f1981195f9904466a39c9948fe30bbff2660be1715a4589334c74c7"
+	expected := "0432c4ae2c1f1981195f9904466a39c9948fe30bbff2660be1715a4589334c74c7" +
+		"bc3736a2f4f6779c59bdcee36b692153d0a9877cc62a474002df32e52139f0a0"
+
+	publicKey, err := encryption.DecodePublicKey(compressed)
+	if err != nil {
+		t.Fatalf("Failed to decode compressed public key: %v", err)
+	}
+	if hex.EncodeToString(encryption.EncodePublicKey(publicKey)) != expected {
+		t.Errorf("Decompressed point mismatch. Got: %x", encryption.EncodePublicKey(publicKey))
+	}
+
+	// The opposite parity prefix must yield the negated point
+	negated, err := encryption.DecodePublicKey("03" + compressed[2:])
+	if err != nil {
+		t.Fatalf("Failed to decode compressed public key: %v", err)
+	}
+	if negated.Y.Cmp(publicKey.Y) == 0 {
+		t.Error("Parity prefix was ignored when decompressing")
+	}
+}
+
+func TestSM2EncryptorDecryptor(t *testing.T) {
+	publicKeyHex, privateKeyHex, err := generateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+
+	encryptor, err := encryption.NewSM2Encryptor(publicKeyHex)
+	if err != nil {
+		t.Fatalf("Failed to create SM2 encryptor: %v", err)
+	}
+	decryptor, err := encryption.NewSM2Decryptor(privateKeyHex)
+	if err != nil {
+		t.Fatalf("Failed to create SM2 decryptor: %v", err)
+	}
+
+	// The decryptor derives the same public key from D
+	if decryptor.PublicKeyHex() != publicKeyHex {
+		t.Errorf("Derived public key mismatch. Expected: %s, Got: %s", publicKeyHex, decryptor.PublicKeyHex())
+	}
+	if encryptor.HasPrivateKey() || !decryptor.HasPrivateKey() {
+		t.Error("HasPrivateKey doesn't reflect the constructor used")
+	}
+
+	plaintext := "Hello, SM2 encryptor!"
+	ciphertext, err := encryptor.Encrypt2Hex(plaintext, 0)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	decrypted, err := decryptor.DecryptHex(ciphertext, 0)
+	if err != nil {
+		t.Fatalf("Decryption failed: %v", err)
+	}
+	if string(decrypted) != plaintext {
+		t.Errorf("Decrypted text doesn't match original. Expected: %s, Got: %s", plaintext, string(decrypted))
+	}
+
+	// A public-key-only instance cannot decrypt or sign
+	if _, err := encryptor.DecryptHex(ciphertext, 0); !errors.Is(err, encryption.ErrPrivateKeyRequired) {
+		t.Errorf("Expected ErrPrivateKeyRequired from DecryptHex, got %v", err)
+	}
+	var obj map[string]any
+	if err := encryptor.DecryptObject(ciphertext, 0, &obj); !errors.Is(err, encryption.ErrPrivateKeyRequired) {
+		t.Errorf("Expected ErrPrivateKeyRequired from DecryptObject, got %v", err)
+	}
+	if _, err := encryptor.Sign([]byte(plaintext), encryption.SignatureASN1); !errors.Is(err, encryption.ErrPrivateKeyRequired) {
+		t.Errorf("Expected ErrPrivateKeyRequired from Sign, got %v", err)
+	}
+	if _, err := encryptor.PrivateKeyPEM(nil); !errors.Is(err, encryption.ErrPrivateKeyRequired) {
+		t.Errorf("Expected ErrPrivateKeyRequired from PrivateKeyPEM, got %v", err)
+	}
+	if encryptor.PrivateKeyHex() != "" {
+		t.Error("PrivateKeyHex should be empty for a public-key-only instance")
+	}
+
+	// Signatures from the decryptor verify with the encryptor
+	signature, err := decryptor.Sign([]byte(plaintext), encryption.SignatureASN1)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if err := encryptor.Verify([]byte(plaintext), signature, encryption.SignatureASN1); err != nil {
+		t.Errorf("Verify with encryptor failed: %v", err)
+	}
+}
+
+// TestSM2DecryptorErrorHandling tests error handling in NewSM2Decryptor
+func TestSM2DecryptorErrorHandling(t *testing.T) {
+	for _, d := range []string{"invalid", "", "00"} {
+		if _, err := encryption.NewSM2Decryptor(d); !errors.Is(err, encryption.ErrInvalidPrivateKey) {
+			t.Errorf("Expected ErrInvalidPrivateKey for D=%q, got %v", d, err)
+		}
+	}
+	if _, err := encryption.NewSM2Encryptor("invalid"); !errors.Is(err, encryption.ErrInvalidPublicKey) {
+		t.Errorf("Expected ErrInvalidPublicKey from NewSM2Encryptor, got %v", err)
+	}
+}
+
+func TestSM2EncryptBytes(t *testing.T) {
+	publicKeyHex, privateKeyHex, err := generateSM2KeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate SM2 key pair: %v", err)
+	}
+	sm2, err := encryption.NewSM2(publicKeyHex, privateKeyHex)
+	if err != nil {
+		t.Fatalf("Failed to create SM2 instance: %v", err)
+	}
+
+	// Binary plaintext including bytes that are not valid UTF-8
+	plaintext := []byte{0x00, 0xff, 0xfe, 0x89, 'P', 'N', 'G', 0x0d, 0x0a}
+	ciphertext, err := sm2.EncryptBytes(plaintext, encryption.CipherC1C3C2)
+	if err != nil {
+		t.Fatalf("EncryptBytes failed: %v", err)
+	}
+	decrypted, err := sm2.Decrypt(ciphertext, encryption.CipherC1C3C2)
+	if err != nil {
+		t.Fatalf("Decryption failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Decrypted data mismatch. Expected: %x, Got: %x", plaintext, decrypted)
+	}
+
+	for _, encoding := range []encryption.Encoding{encryption.EncodingHex, encryption.EncodingBase64, encryption.EncodingBase64URL, encryption.EncodingRaw} {
+		encoded, err := sm2.EncryptEncoded(plaintext, encryption.CipherASN1, encoding)
+		if err != nil {
+			t.Fatalf("%v: EncryptEncoded failed: %v", encoding, err)
+		}
+		decrypted, err := sm2.DecryptEncoded(encoded, encryption.CipherASN1, encoding)
+		if err != nil {
+			t.Fatalf("%v: DecryptEncoded failed: %v", encoding, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("%v: Decrypted data mismatch", encoding)
+		}
+	}
+
+	// Hex output matches the existing helper family
+	encoded, err := sm2.EncryptEncoded([]byte("Hello"), encryption.CipherC1C3C2, encryption.EncodingHex)
+	if err != nil {
+		t.Fatalf("EncryptEncoded failed: %v", err)
+	}
+	if decrypted, err := sm2.DecryptHex(encoded, encryption.CipherC1C3C2); err != nil || string(decrypted) != "Hello" {
+		t.Errorf("DecryptHex of EncryptEncoded output failed: %v", err)
+	}
+
+	// Empty plaintext is rejected instead of hanging in the KDF retry loop
+	if _, err := sm2.EncryptBytes(nil, encryption.CipherC1C3C2); !errors.Is(err, encryption.ErrEmptyPlaintext) {
+		t.Errorf("Expected ErrEmptyPlaintext, got: %v", err)
+	}
+	if _, err := sm2.Encrypt2Hex("", encryption.CipherC1C3C2); !errors.Is(err, encryption.ErrEmptyPlaintext) {
+		t.Errorf("Expected ErrEmptyPlaintext from Encrypt2Hex, got: %v", err)
+	}
+	if _, err := sm2.DecryptEncoded("zz", encryption.CipherC1C3C2, encryption.EncodingHex); err == nil {
+		t.Error("Expected error for invalid hex ciphertext")
 	}
 }
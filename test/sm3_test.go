@@ -1,7 +1,12 @@
 package test
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"hash"
+	"strings"
 	"testing"
 
 	"xyz/test/helloworld/encryption"
@@ -56,3 +61,179 @@ func TestSM3HashingDifferentInputs(t *testing.T) {
 		t.Error("SM3 hashes of different inputs should not be equal")
 	}
 }
+
+// GB/T 32905-2016 Appendix A vectors
+var sm3Vectors = []struct {
+	input    string
+	expected string
+}{
+	{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+	{strings.Repeat("abcd", 16), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+}
+
+func TestSM3Sum(t *testing.T) {
+	for _, v := range sm3Vectors {
+		if got := encryption.SM3Sum2Hex([]byte(v.input)); got != v.expected {
+			t.Errorf("SM3Sum2Hex(%q) mismatch. Expected: %s, Got: %s", v.input, v.expected, got)
+		}
+		expected, _ := hex.DecodeString(v.expected)
+		if got := encryption.SM3Sum2Base64([]byte(v.input)); got != base64.StdEncoding.EncodeToString(expected) {
+			t.Errorf("SM3Sum2Base64(%q) mismatch. Got: %s", v.input, got)
+		}
+		sum, err := encryption.SM3SumReader(strings.NewReader(v.input))
+		if err != nil {
+			t.Fatalf("SM3SumReader failed: %v", err)
+		}
+		if !bytes.Equal(sum, expected) {
+			t.Errorf("SM3SumReader(%q) mismatch. Expected: %s, Got: %x", v.input, v.expected, sum)
+		}
+	}
+}
+
+func TestSM3Streaming(t *testing.T) {
+	// 1 MiB written in uneven chunks must match the one-shot digest
+	data := make([]byte, 1<<20)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	expected := encryption.SM3Sum(data)
+
+	var h hash.Hash = encryption.NewSM3()
+	for offset, chunk := 0, 1; offset < len(data); chunk = chunk*3 + 1 {
+		end := min(offset+chunk, len(data))
+		h.Write(data[offset:end])
+		offset = end
+	}
+	if !bytes.Equal(h.Sum(nil), expected) {
+		t.Error("Incremental SM3 doesn't match one-shot digest")
+	}
+
+	// Sum appends to its argument and leaves the state untouched
+	prefix := []byte("prefix")
+	sum := h.Sum(prefix)
+	if !bytes.Equal(sum[:len(prefix)], prefix) || !bytes.Equal(sum[len(prefix):], expected) {
+		t.Error("Sum should append the digest to its argument")
+	}
+	if !bytes.Equal(h.Sum(nil), expected) {
+		t.Error("Sum should not change the hash state")
+	}
+	if h.Size() != encryption.SM3Size || h.BlockSize() != 64 {
+		t.Errorf("Unexpected Size/BlockSize: %d/%d", h.Size(), h.BlockSize())
+	}
+
+	h.Reset()
+	h.Write([]byte("abc"))
+	if got := h.(*encryption.SM3).Sum2Hex(); got != sm3Vectors[0].expected {
+		t.Errorf("Reset hash mismatch. Expected: %s, Got: %s", sm3Vectors[0].expected, got)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestSM3SumReaderError(t *testing.T) {
+	if _, err := encryption.SM3SumReader(failingReader{}); err == nil {
+		t.Error("Expected error from failing reader")
+	}
+}
+
+// HMAC-SM3 vectors using the key/data pairs of RFC 4231 test cases 1-4, 6 and 7,
+// computed with `openssl mac -digest SM3 -macopt hexkey:<key> HMAC`.
+var hmacSM3Vectors = []struct {
+	name     string
+	keyHex   string
+	data     []byte
+	expected string
+}{
+	{"Case1", strings.Repeat("0b", 20), []byte("Hi There"),
+		"51b00d1fb49832bfb01c3ce27848e59f871d9ba938dc563b338ca964755cce70"},
+	{"Case2", "4a656665", []byte("what do ya want for nothing?"),
+		"2e87f1d16862e6d964b50a5200bf2b10b764faa9680a296a2405f24bec39f882"},
+	{"Case3", strings.Repeat("aa", 20), bytes.Repeat([]byte{0xdd}, 50),
+		"dd9421e1c725bdf52ec1aa34edadb3c97f5951a83a2fa93f73a7902bc1dcc777"},
+	{"Case4", "0102030405060708090a0b0c0d0e0f10111213141516171819", bytes.Repeat([]byte{0xcd}, 50),
+		"b57c79be03472aeb8cada581dea332cb2ba83d19cb1b052dd07194def75fb8cd"},
+	{"Case6", strings.Repeat("aa", 131), []byte("Test Using Larger Than Block-Size Key - Hash Key First"),
+		"b4fd844e13342002f0b2e0690ea7741f1497d993a70494cea601e657bedf67a0"},
+	{"Case7", strings.Repeat("aa", 131), []byte("This is a test using a larger than block-size key and a larger than block-size data. " +
+		"The key needs to be hashed before being used by the HMAC algorithm."),
+		"5acbdeb0c8c1ef3a99088fe51c0a1d5f4e1c175935f016aee74eb8056db18acb"},
+}
+
+func TestHMACSM3Vectors(t *testing.T) {
+	for _, v := range hmacSM3Vectors {
+		t.Run(v.name, func(t *testing.T) {
+			key, _ := hex.DecodeString(v.keyHex)
+			expected, _ := hex.DecodeString(v.expected)
+
+			if got := encryption.HMACSM3Sum2Hex(key, v.data); got != v.expected {
+				t.Errorf("HMACSM3Sum2Hex mismatch. Expected: %s, Got: %s", v.expected, got)
+			}
+			if got := encryption.HMACSM3Sum2Base64(key, v.data); got != base64.StdEncoding.EncodeToString(expected) {
+				t.Errorf("HMACSM3Sum2Base64 mismatch. Got: %s", got)
+			}
+			sum, err := encryption.HMACSM3SumReader(key, bytes.NewReader(v.data))
+			if err != nil {
+				t.Fatalf("HMACSM3SumReader failed: %v", err)
+			}
+			if !bytes.Equal(sum, expected) {
+				t.Errorf("HMACSM3SumReader mismatch. Expected: %s, Got: %x", v.expected, sum)
+			}
+
+			// Byte-at-a-time streaming
+			mac := encryption.NewHMACSM3(key)
+			for i := range v.data {
+				mac.Write(v.data[i : i+1])
+			}
+			if !bytes.Equal(mac.Sum(nil), expected) {
+				t.Error("Streaming HMAC-SM3 mismatch")
+			}
+		})
+	}
+}
+
+func TestVerifyHMACSM3(t *testing.T) {
+	key := []byte("webhook-secret")
+	payload := []byte(`{"event":"payment.succeeded","amount":100}`)
+	mac := encryption.HMACSM3Sum(key, payload)
+
+	if err := encryption.VerifyHMACSM3(key, payload, mac); err != nil {
+		t.Errorf("VerifyHMACSM3 failed: %v", err)
+	}
+	if err := encryption.VerifyHMACSM3Hex(key, payload, hex.EncodeToString(mac)); err != nil {
+		t.Errorf("VerifyHMACSM3Hex failed: %v", err)
+	}
+	if err := encryption.VerifyHMACSM3Base64(key, payload, base64.StdEncoding.EncodeToString(mac)); err != nil {
+		t.Errorf("VerifyHMACSM3Base64 failed: %v", err)
+	}
+	if err := encryption.VerifyHMACSM3Reader(key, bytes.NewReader(payload), mac); err != nil {
+		t.Errorf("VerifyHMACSM3Reader failed: %v", err)
+	}
+
+	tampered := append([]byte(nil), mac...)
+	tampered[0] ^= 0x01
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"TamperedMAC", encryption.VerifyHMACSM3(key, payload, tampered)},
+		{"TruncatedMAC", encryption.VerifyHMACSM3(key, payload, mac[:16])},
+		{"WrongKey", encryption.VerifyHMACSM3([]byte("other-secret"), payload, mac)},
+		{"TamperedPayload", encryption.VerifyHMACSM3(key, append(payload, ' '), mac)},
+		{"InvalidHex", encryption.VerifyHMACSM3Hex(key, payload, "zz")},
+		{"InvalidBase64", encryption.VerifyHMACSM3Base64(key, payload, "!!")},
+		{"ReaderTampered", encryption.VerifyHMACSM3Reader(key, bytes.NewReader(payload), tampered)},
+	}
+	for _, c := range cases {
+		if !errors.Is(c.err, encryption.ErrInvalidMAC) {
+			t.Errorf("%s: expected ErrInvalidMAC, got: %v", c.name, c.err)
+		}
+	}
+
+	if err := encryption.VerifyHMACSM3Reader(key, failingReader{}, mac); err == nil || errors.Is(err, encryption.ErrInvalidMAC) {
+		t.Errorf("Expected read error, got: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"xyz/test/helloworld/encryption"
+)
+
+// Test vectors from RFC 8998 Appendix A.
+const (
+	rfc8998Key       = "0123456789ABCDEFFEDCBA9876543210"
+	rfc8998Nonce     = "00001234567800000000ABCD"
+	rfc8998AAD       = "FEEDFACEDEADBEEFFEEDFACEDEADBEEFABADDAD2"
+	rfc8998Plaintext = "AAAAAAAAAAAAAAAABBBBBBBBBBBBBBBBCCCCCCCCCCCCCCCCDDDDDDDDDDDDDDDD" +
+		"EEEEEEEEEEEEEEEEFFFFFFFFFFFFFFFFEEEEEEEEEEEEEEEEAAAAAAAAAAAAAAAA"
+)
+
+func TestSM4AEADVectors(t *testing.T) {
+	cases := []struct {
+		mode       encryption.AEADMode
+		ciphertext string
+		tag        string
+	}{
+		{
+			encryption.AEADGCM,
+			"17F399F08C67D5EE19D0DC9969C4BB7D5FD46FD3756489069157B282BB200735" +
+				"D82710CA5C22F0CCFA7CBF93D496AC15A56834CBCF98C397B4024A2691233B8D",
+			"83DE3541E4C2B58177E065A9BF7B62EC",
+		},
+		{
+			encryption.AEADCCM,
+			"48AF93501FA62ADBCD414CCE6034D895DDA1BF8F132F042098661572E7483094" +
+				"FD12E518CE062C98ACEE28D95DF4416BED31A2F04476C18BB40C84A74B97DC5B",
+			"16842D4FA186F56AB33256971FA110F4",
+		},
+	}
+	nonce := mustDecodeHex(t, rfc8998Nonce)
+	aad := mustDecodeHex(t, rfc8998AAD)
+	plaintext := mustDecodeHex(t, rfc8998Plaintext)
+
+	for _, c := range cases {
+		aead, err := encryption.AEADFromHex(rfc8998Key, c.mode)
+		if err != nil {
+			t.Fatalf("%v: AEADFromHex failed: %v", c.mode, err)
+		}
+		sealed, err := aead.Seal(nonce, plaintext, aad)
+		if err != nil {
+			t.Fatalf("%v: Seal failed: %v", c.mode, err)
+		}
+		expected := strings.ToLower(c.ciphertext + c.tag)
+		if hex.EncodeToString(sealed) != expected {
+			t.Errorf("%v: Seal mismatch. Expected: %s, Got: %x", c.mode, expected, sealed)
+		}
+
+		opened, err := aead.Open(nonce, sealed, aad)
+		if err != nil {
+			t.Fatalf("%v: Open failed: %v", c.mode, err)
+		}
+		if !bytes.Equal(opened, plaintext) {
+			t.Errorf("%v: Open mismatch", c.mode)
+		}
+	}
+}
+
+func TestSM4AEADEncryption(t *testing.T) {
+	for _, mode := range []encryption.AEADMode{encryption.AEADGCM, encryption.AEADCCM} {
+		aead, err := encryption.AEADFromHex(rfc8998Key, mode)
+		if err != nil {
+			t.Fatalf("%v: AEADFromHex failed: %v", mode, err)
+		}
+		aad := []byte("order-42")
+		plaintext := "Hello, SM4 authenticated encryption!"
+
+		hexCiphertext, err := aead.Encrypt2Hex(plaintext, aad)
+		if err != nil {
+			t.Fatalf("%v: Encrypt2Hex failed: %v", mode, err)
+		}
+		decrypted, err := aead.DecryptHex(hexCiphertext, aad)
+		if err != nil {
+			t.Fatalf("%v: DecryptHex failed: %v", mode, err)
+		}
+		if string(decrypted) != plaintext {
+			t.Errorf("%v: Decrypted text mismatch: %s", mode, decrypted)
+		}
+
+		// A fresh nonce per message: identical plaintexts give different ciphertexts
+		again, err := aead.Encrypt2Hex(plaintext, aad)
+		if err != nil {
+			t.Fatalf("%v: Encrypt2Hex failed: %v", mode, err)
+		}
+		if again == hexCiphertext {
+			t.Errorf("%v: Ciphertexts should differ between encryptions", mode)
+		}
+
+		base64Ciphertext, err := aead.Encrypt2Base64("", nil)
+		if err != nil {
+			t.Fatalf("%v: Encrypt2Base64 failed: %v", mode, err)
+		}
+		decrypted, err = aead.DecryptBase64(base64Ciphertext, nil)
+		if err != nil || len(decrypted) != 0 {
+			t.Errorf("%v: DecryptBase64 of empty plaintext failed: %v %q", mode, err, decrypted)
+		}
+
+		type TestObject struct {
+			Name  string `json:"name"`
+			Value int    `json:"value"`
+		}
+		encryptedObj, err := aead.EncryptObject(TestObject{Name: "test", Value: 123}, aad)
+		if err != nil {
+			t.Fatalf("%v: EncryptObject failed: %v", mode, err)
+		}
+		var decryptedObj TestObject
+		if err := aead.DecryptObject(hex.EncodeToString(encryptedObj), aad, &decryptedObj); err != nil {
+			t.Fatalf("%v: DecryptObject failed: %v", mode, err)
+		}
+		if decryptedObj.Name != "test" || decryptedObj.Value != 123 {
+			t.Errorf("%v: Decrypted object mismatch: %+v", mode, decryptedObj)
+		}
+
+		// Binary plaintext through the []byte and encoding-agnostic APIs
+		binary := []byte{0x00, 0xff, 0xfe, 0x89, 'P', 'N', 'G'}
+		sealed, err := aead.EncryptBytes(binary, aad)
+		if err != nil {
+			t.Fatalf("%v: EncryptBytes failed: %v", mode, err)
+		}
+		if opened, err := aead.Decrypt(sealed, aad); err != nil || !bytes.Equal(opened, binary) {
+			t.Errorf("%v: Decrypt of EncryptBytes output failed: %v", mode, err)
+		}
+		for _, encoding := range []encryption.Encoding{encryption.EncodingHex, encryption.EncodingBase64, encryption.EncodingBase64URL, encryption.EncodingRaw} {
+			encoded, err := aead.EncryptEncoded(binary, aad, encoding)
+			if err != nil {
+				t.Fatalf("%v/%v: EncryptEncoded failed: %v", mode, encoding, err)
+			}
+			opened, err := aead.DecryptEncoded(encoded, aad, encoding)
+			if err != nil || !bytes.Equal(opened, binary) {
+				t.Errorf("%v/%v: DecryptEncoded failed: %v", mode, encoding, err)
+			}
+		}
+	}
+}
+
+func TestSM4AEADTampering(t *testing.T) {
+	for _, mode := range []encryption.AEADMode{encryption.AEADGCM, encryption.AEADCCM} {
+		aead, err := encryption.AEADFromHex(rfc8998Key, mode)
+		if err != nil {
+			t.Fatalf("%v: AEADFromHex failed: %v", mode, err)
+		}
+		aad := []byte("header")
+		ciphertext, err := aead.Encrypt("attack at dawn", aad)
+		if err != nil {
+			t.Fatalf("%v: Encrypt failed: %v", mode, err)
+		}
+
+		for i := range ciphertext {
+			tampered := append([]byte(nil), ciphertext...)
+			tampered[i] ^= 0x01
+			if _, err := aead.Decrypt(tampered, aad); !errors.Is(err, encryption.ErrSM4Authentication) {
+				t.Fatalf("%v: Expected ErrSM4Authentication for tampered byte %d, got: %v", mode, i, err)
+			}
+		}
+		if _, err := aead.Decrypt(ciphertext, []byte("other")); !errors.Is(err, encryption.ErrSM4Authentication) {
+			t.Errorf("%v: Expected ErrSM4Authentication for wrong AAD, got: %v", mode, err)
+		}
+		if _, err := aead.Decrypt(ciphertext[:aead.NonceSize()+aead.Overhead()-1], aad); !errors.Is(err, encryption.ErrSM4Authentication) {
+			t.Errorf("%v: Expected ErrSM4Authentication for short ciphertext, got: %v", mode, err)
+		}
+
+		otherKey, err := encryption.AEADFromHex("FEDCBA98765432100123456789ABCDEF", mode)
+		if err != nil {
+			t.Fatalf("%v: AEADFromHex failed: %v", mode, err)
+		}
+		if _, err := otherKey.Decrypt(ciphertext, aad); !errors.Is(err, encryption.ErrSM4Authentication) {
+			t.Errorf("%v: Expected ErrSM4Authentication for wrong key, got: %v", mode, err)
+		}
+	}
+}
+
+func TestSM4AEADErrorHandling(t *testing.T) {
+	if _, err := encryption.AEADFromHex("0123", encryption.AEADGCM); err == nil {
+		t.Error("Expected error for short key")
+	}
+	if _, err := encryption.AEADFromBase64("invalid!", encryption.AEADGCM); err == nil {
+		t.Error("Expected error for invalid base64 key")
+	}
+	if _, err := encryption.AEADFromHex(rfc8998Key, encryption.AEADMode(99)); err == nil {
+		t.Error("Expected error for unsupported mode")
+	}
+
+	aead, err := encryption.AEADFromHex(rfc8998Key, encryption.AEADCCM)
+	if err != nil {
+		t.Fatalf("AEADFromHex failed: %v", err)
+	}
+	if _, err := aead.Seal(make([]byte, 8), []byte("data"), nil); !errors.Is(err, encryption.ErrInvalidNonce) {
+		t.Errorf("Expected ErrInvalidNonce, got: %v", err)
+	}
+	if _, err := aead.Open(make([]byte, 8), []byte("data"), nil); !errors.Is(err, encryption.ErrInvalidNonce) {
+		t.Errorf("Expected ErrInvalidNonce, got: %v", err)
+	}
+	if _, err := aead.DecryptHex("invalid", nil); err == nil {
+		t.Error("Expected error for invalid hex string")
+	}
+}
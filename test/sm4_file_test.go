@@ -0,0 +1,177 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"xyz/test/helloworld/encryption"
+)
+
+// The golden files in testdata were written by EncodeFile with key
+// 0123456789ABCDEFFEDCBA9876543210 and key ID "golden-key-1":
+//
+//	sm4file_v1.sm4f        sm4file_plaintext.txt in 64-byte chunks (3 chunks)
+//	sm4file_v1_empty.sm4f  empty plaintext, default chunk size (1 empty final chunk)
+//
+// They pin format version 1 and must keep decrypting; do not regenerate them.
+const goldenFileKeyID = "golden-key-1"
+
+func goldenFileKeys(t *testing.T) encryption.FileKeyFunc {
+	key := mustDecodeHex(t, "0123456789ABCDEFFEDCBA9876543210")
+	return func(keyID string) ([]byte, error) {
+		if keyID != goldenFileKeyID {
+			return nil, fmt.Errorf("unknown key %q", keyID)
+		}
+		return key, nil
+	}
+}
+
+func TestFileGolden(t *testing.T) {
+	keys := goldenFileKeys(t)
+	cases := []struct {
+		file      string
+		plaintext []byte
+		chunkSize int
+	}{
+		{"sm4file_v1.sm4f", readFixture(t, "sm4file_plaintext.txt"), 64},
+		{"sm4file_v1_empty.sm4f", nil, encryption.StreamChunkSize},
+	}
+	for _, c := range cases {
+		data := readFixture(t, c.file)
+		h, err := encryption.ReadFileHeader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: ReadFileHeader failed: %v", c.file, err)
+		}
+		if h.Version != encryption.FileVersion || h.Algorithm != encryption.AEADGCM ||
+			h.ChunkSize != c.chunkSize || h.KeyID != goldenFileKeyID || len(h.NoncePrefix) != 7 {
+			t.Errorf("%s: Unexpected header: %+v", c.file, h)
+		}
+
+		plaintext, err := encryption.DecodeFile(data, keys)
+		if err != nil {
+			t.Fatalf("%s: DecodeFile failed: %v", c.file, err)
+		}
+		if !bytes.Equal(plaintext, c.plaintext) {
+			t.Errorf("%s: Plaintext mismatch. Expected: %q, Got: %q", c.file, c.plaintext, plaintext)
+		}
+	}
+}
+
+func TestFileRoundTrip(t *testing.T) {
+	key := mustDecodeHex(t, "FEDCBA98765432100123456789ABCDEF")
+	keys := func(keyID string) ([]byte, error) {
+		if keyID != "2024-q3" {
+			return nil, fmt.Errorf("unknown key %q", keyID)
+		}
+		return key, nil
+	}
+	for _, chunkSize := range []int{0, 1, 100} {
+		for _, size := range []int{0, 1, 99, 100, 101, 1000} {
+			data := randomBytes(t, size)
+			opts := &encryption.FileOptions{KeyID: "2024-q3", ChunkSize: chunkSize}
+
+			var file bytes.Buffer
+			w, err := encryption.NewFileWriter(&file, key, opts)
+			if err != nil {
+				t.Fatalf("NewFileWriter failed: %v", err)
+			}
+			encryptStream(t, w, data)
+
+			r, err := encryption.NewFileReader(bytes.NewReader(file.Bytes()), keys)
+			if err != nil {
+				t.Fatalf("NewFileReader failed: %v", err)
+			}
+			decrypted, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("chunk=%d size=%d: Decryption failed: %v", chunkSize, size, err)
+			}
+			if !bytes.Equal(decrypted, data) {
+				t.Fatalf("chunk=%d size=%d: Plaintext mismatch", chunkSize, size)
+			}
+		}
+	}
+
+	// Files written with the same key differ because of the random nonce prefix
+	first, err := encryption.EncodeFile([]byte("same"), key, nil)
+	if err != nil {
+		t.Fatalf("EncodeFile failed: %v", err)
+	}
+	second, err := encryption.EncodeFile([]byte("same"), key, nil)
+	if err != nil {
+		t.Fatalf("EncodeFile failed: %v", err)
+	}
+	if bytes.Equal(first, second) {
+		t.Error("Encrypted files should differ")
+	}
+}
+
+func TestFileTampering(t *testing.T) {
+	keys := goldenFileKeys(t)
+	golden := readFixture(t, "sm4file_v1.sm4f")
+	headerSize := 11 + len(goldenFileKeyID) + 7
+
+	modify := func(offset int, value byte) []byte {
+		data := bytes.Clone(golden)
+		data[offset] = value
+		return data
+	}
+	cases := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"BadMagic", modify(0, 'X'), encryption.ErrInvalidFile},
+		{"UnknownVersion", modify(4, 2), encryption.ErrInvalidFile},
+		{"UnknownAlgorithm", modify(5, 9), encryption.ErrInvalidFile},
+		{"ZeroChunkSize", modify(9, 0), encryption.ErrInvalidFile},
+		{"HugeChunkSize", modify(6, 0xff), encryption.ErrInvalidFile},
+		{"TruncatedHeader", golden[:headerSize-1], encryption.ErrInvalidFile},
+		{"ChangedChunkSize", modify(9, 63), encryption.ErrSM4Authentication},
+		{"ChangedKeyID", modify(11, 'G'), nil},
+		{"ChangedNoncePrefix", modify(headerSize-1, golden[headerSize-1]^0x01), encryption.ErrSM4Authentication},
+		{"ChangedChunk", modify(headerSize, golden[headerSize]^0x01), encryption.ErrSM4Authentication},
+		{"DroppedFinalChunk", golden[:headerSize+2*(64+16)], encryption.ErrSM4Authentication},
+		{"HeaderOnly", golden[:headerSize], encryption.ErrSM4Authentication},
+	}
+	for _, c := range cases {
+		_, err := encryption.DecodeFile(c.data, keys)
+		if c.expected == nil {
+			// The key ID is unauthenticated until the first chunk is opened; here the lookup fails first
+			if err == nil || !strings.Contains(err.Error(), "unknown key") {
+				t.Errorf("%s: Expected key lookup error, got: %v", c.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, c.expected) {
+			t.Errorf("%s: Expected %v, got: %v", c.name, c.expected, err)
+		}
+	}
+
+	// A key ID edited to one the lookup accepts is still caught by authentication
+	anyKey := func(string) ([]byte, error) { return keys(goldenFileKeyID) }
+	if _, err := encryption.DecodeFile(modify(11, 'G'), anyKey); !errors.Is(err, encryption.ErrSM4Authentication) {
+		t.Errorf("Expected ErrSM4Authentication for edited key ID, got: %v", err)
+	}
+
+	wrongKey := func(string) ([]byte, error) { return mustDecodeHex(t, "FEDCBA98765432100123456789ABCDEF"), nil }
+	if _, err := encryption.DecodeFile(golden, wrongKey); !errors.Is(err, encryption.ErrSM4Authentication) {
+		t.Errorf("Expected ErrSM4Authentication for wrong key, got: %v", err)
+	}
+}
+
+func TestFileOptionsValidation(t *testing.T) {
+	key := mustDecodeHex(t, "0123456789ABCDEFFEDCBA9876543210")
+	if _, err := encryption.EncodeFile(nil, key, &encryption.FileOptions{KeyID: strings.Repeat("k", 256)}); !errors.Is(err, encryption.ErrInvalidFile) {
+		t.Errorf("Expected ErrInvalidFile for long key ID, got: %v", err)
+	}
+	if _, err := encryption.EncodeFile(nil, key, &encryption.FileOptions{ChunkSize: 16*1024*1024 + 1}); !errors.Is(err, encryption.ErrInvalidFile) {
+		t.Errorf("Expected ErrInvalidFile for oversized chunks, got: %v", err)
+	}
+	if _, err := encryption.EncodeFile(nil, key[:8], nil); !errors.Is(err, encryption.ErrSM4InvalidKey) {
+		t.Errorf("Expected ErrSM4InvalidKey, got: %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"xyz/test/helloworld/encryption"
+)
+
+// streamSizes cover empty input, partial blocks and the 32 KiB internal buffer boundaries.
+var streamSizes = []int{0, 1, 15, 16, 17, 32*1024 - 1, 32 * 1024, 32*1024 + 16, 100000}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("Failed to read random bytes: %v", err)
+	}
+	return b
+}
+
+// encryptStream writes data through w in small, uneven pieces.
+func encryptStream(t *testing.T, w io.WriteCloser, data []byte) {
+	t.Helper()
+	for len(data) > 0 {
+		n := min(len(data), 1000)
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatalf("Stream write failed: %v", err)
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Stream close failed: %v", err)
+	}
+}
+
+func TestSM4StreamMatchesEncrypt(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+	iv, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+
+	for _, mode := range []encryption.SM4Mode{encryption.ModeCBC, encryption.ModeECB, encryption.ModeCTR, encryption.ModeCFB, encryption.ModeOFB} {
+		sm4, err := encryption.NewSM4(key, iv, encryption.WithMode(mode), encryption.WithFixedIV())
+		if err != nil {
+			t.Fatalf("%v: Failed to create SM4 instance: %v", mode, err)
+		}
+		for _, size := range streamSizes {
+			data := randomBytes(t, size)
+			expected, err := sm4.Encrypt(string(data))
+			if err != nil {
+				t.Fatalf("%v: Encryption failed: %v", mode, err)
+			}
+
+			var ciphertext bytes.Buffer
+			w, err := sm4.NewEncryptWriter(&ciphertext)
+			if err != nil {
+				t.Fatalf("%v: NewEncryptWriter failed: %v", mode, err)
+			}
+			encryptStream(t, w, data)
+			if !bytes.Equal(ciphertext.Bytes(), expected) {
+				t.Fatalf("%v: Streamed ciphertext of %d bytes differs from Encrypt", mode, size)
+			}
+
+			r, err := sm4.NewDecryptReader(iotest.HalfReader(bytes.NewReader(expected)))
+			if err != nil {
+				t.Fatalf("%v: NewDecryptReader failed: %v", mode, err)
+			}
+			decrypted, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("%v: Stream decryption of %d bytes failed: %v", mode, size, err)
+			}
+			if !bytes.Equal(decrypted, data) {
+				t.Fatalf("%v: Streamed plaintext of %d bytes mismatch", mode, size)
+			}
+		}
+	}
+}
+
+func TestSM4StreamRandomIV(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+	sm4, err := encryption.NewSM4(key, nil, encryption.WithRandomIV())
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	data := randomBytes(t, 50000)
+
+	var ciphertext bytes.Buffer
+	w, err := sm4.NewEncryptWriter(&ciphertext)
+	if err != nil {
+		t.Fatalf("NewEncryptWriter failed: %v", err)
+	}
+	encryptStream(t, w, data)
+
+	// Streamed output is interchangeable with Decrypt
+	decrypted, err := sm4.Decrypt(ciphertext.Bytes())
+	if err != nil || !bytes.Equal(decrypted, data) {
+		t.Fatalf("Decrypt of streamed ciphertext failed: %v", err)
+	}
+	r, err := sm4.NewDecryptReader(bytes.NewReader(ciphertext.Bytes()))
+	if err != nil {
+		t.Fatalf("NewDecryptReader failed: %v", err)
+	}
+	if err := iotest.TestReader(r, data); err != nil {
+		t.Errorf("Decrypt reader misbehaves: %v", err)
+	}
+
+	if _, err := sm4.NewDecryptReader(bytes.NewReader(make([]byte, 8))); !errors.Is(err, encryption.ErrSM4InvalidCiphertext) {
+		t.Errorf("Expected ErrSM4InvalidCiphertext for missing IV, got: %v", err)
+	}
+}
+
+func TestSM4StreamErrors(t *testing.T) {
+	keyHex := "0123456789ABCDEFFEDCBA9876543210"
+	ivHex := "000102030405060708090A0B0C0D0E0F"
+	sm4, err := encryption.FromHex(keyHex, ivHex)
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	ciphertext, err := sm4.Encrypt(string(randomBytes(t, 40000)))
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+
+	for _, truncated := range [][]byte{nil, ciphertext[:len(ciphertext)-1]} {
+		r, err := sm4.NewDecryptReader(bytes.NewReader(truncated))
+		if err != nil {
+			t.Fatalf("NewDecryptReader failed: %v", err)
+		}
+		if _, err := io.ReadAll(r); !errors.Is(err, encryption.ErrSM4InvalidCiphertext) {
+			t.Errorf("Expected ErrSM4InvalidCiphertext for %d-byte input, got: %v", len(truncated), err)
+		}
+	}
+
+	wrongKey, err := encryption.FromHex("FEDCBA98765432100123456789ABCDEF", ivHex)
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	r, err := wrongKey.NewDecryptReader(bytes.NewReader(ciphertext))
+	if err != nil {
+		t.Fatalf("NewDecryptReader failed: %v", err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, encryption.ErrInvalidPadding) {
+		t.Errorf("Expected ErrInvalidPadding for wrong key, got: %v", err)
+	}
+
+	r, err = sm4.NewDecryptReader(iotest.TimeoutReader(bytes.NewReader(ciphertext)))
+	if err != nil {
+		t.Fatalf("NewDecryptReader failed: %v", err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, iotest.ErrTimeout) {
+		t.Errorf("Expected the underlying read error, got: %v", err)
+	}
+
+	w, err := sm4.NewEncryptWriter(io.Discard)
+	if err != nil {
+		t.Fatalf("NewEncryptWriter failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := w.Write([]byte("late")); err == nil {
+		t.Error("Expected error writing after Close")
+	}
+}
+
+func TestSM4AEADStream(t *testing.T) {
+	sizes := []int{0, 1, encryption.StreamChunkSize - 1, encryption.StreamChunkSize, encryption.StreamChunkSize + 1, 3 * encryption.StreamChunkSize}
+	for _, mode := range []encryption.AEADMode{encryption.AEADGCM, encryption.AEADCCM} {
+		aead, err := encryption.AEADFromHex(rfc8998Key, mode)
+		if err != nil {
+			t.Fatalf("%v: AEADFromHex failed: %v", mode, err)
+		}
+		aad := []byte("backup-2024.tar")
+		for _, size := range sizes {
+			data := randomBytes(t, size)
+			var ciphertext bytes.Buffer
+			w, err := aead.NewEncryptWriter(&ciphertext, aad)
+			if err != nil {
+				t.Fatalf("%v: NewEncryptWriter failed: %v", mode, err)
+			}
+			encryptStream(t, w, data)
+
+			// The last chunk carries the final marker and is empty only for empty input
+			chunks := max(1, (size+encryption.StreamChunkSize-1)/encryption.StreamChunkSize)
+			if expected := 7 + size + chunks*aead.Overhead(); ciphertext.Len() != expected {
+				t.Errorf("%v: Expected %d ciphertext bytes for %d-byte input, got %d", mode, expected, size, ciphertext.Len())
+			}
+
+			r, err := aead.NewDecryptReader(iotest.OneByteReader(bytes.NewReader(ciphertext.Bytes())), aad)
+			if err != nil {
+				t.Fatalf("%v: NewDecryptReader failed: %v", mode, err)
+			}
+			decrypted, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("%v: Stream decryption of %d bytes failed: %v", mode, size, err)
+			}
+			if !bytes.Equal(decrypted, data) {
+				t.Fatalf("%v: Streamed plaintext of %d bytes mismatch", mode, size)
+			}
+		}
+	}
+}
+
+func TestSM4AEADStreamTampering(t *testing.T) {
+	aead, err := encryption.AEADFromHex(rfc8998Key, encryption.AEADGCM)
+	if err != nil {
+		t.Fatalf("AEADFromHex failed: %v", err)
+	}
+	data := randomBytes(t, 2*encryption.StreamChunkSize+100)
+	var buf bytes.Buffer
+	w, err := aead.NewEncryptWriter(&buf, nil)
+	if err != nil {
+		t.Fatalf("NewEncryptWriter failed: %v", err)
+	}
+	encryptStream(t, w, data)
+	ciphertext := buf.Bytes()
+
+	sealedChunk := encryption.StreamChunkSize + aead.Overhead()
+	first, second := ciphertext[7:7+sealedChunk], ciphertext[7+sealedChunk:7+2*sealedChunk]
+	flipped := bytes.Clone(ciphertext)
+	flipped[len(flipped)-1] ^= 0x01
+
+	cases := map[string][]byte{
+		"FlippedBit":         flipped,
+		"TruncatedAtChunk":   ciphertext[:7+2*sealedChunk],
+		"TruncatedMidChunk":  ciphertext[:len(ciphertext)-5],
+		"SwappedChunks":      append(append(append(bytes.Clone(ciphertext[:7]), second...), first...), ciphertext[7+2*sealedChunk:]...),
+		"DroppedChunk":       append(bytes.Clone(ciphertext[:7+sealedChunk]), ciphertext[7+2*sealedChunk:]...),
+		"PrefixOnly":         ciphertext[:7],
+		"TrailingGarbage":    append(bytes.Clone(ciphertext), 0x00),
+		"WrongNoncePrefix":   append([]byte{0, 0, 0, 0, 0, 0, 0}, ciphertext[7:]...),
+		"FirstChunkOnly":     ciphertext[:7+sealedChunk],
+		"OnlyLastChunkFinal": append(bytes.Clone(ciphertext[:7]), ciphertext[7+2*sealedChunk:]...),
+	}
+	for name, tampered := range cases {
+		r, err := aead.NewDecryptReader(bytes.NewReader(tampered), nil)
+		if err != nil {
+			t.Fatalf("%s: NewDecryptReader failed: %v", name, err)
+		}
+		if _, err := io.ReadAll(r); !errors.Is(err, encryption.ErrSM4Authentication) {
+			t.Errorf("%s: Expected ErrSM4Authentication, got: %v", name, err)
+		}
+	}
+
+	r, err := aead.NewDecryptReader(bytes.NewReader(ciphertext), []byte("other"))
+	if err != nil {
+		t.Fatalf("NewDecryptReader failed: %v", err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, encryption.ErrSM4Authentication) {
+		t.Errorf("Expected ErrSM4Authentication for wrong AAD, got: %v", err)
+	}
+	if _, err := aead.NewDecryptReader(bytes.NewReader(ciphertext[:3]), nil); !errors.Is(err, encryption.ErrSM4Authentication) {
+		t.Errorf("Expected ErrSM4Authentication for missing nonce prefix, got: %v", err)
+	}
+}
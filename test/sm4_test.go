@@ -3,6 +3,8 @@ package test
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
+	"sync"
 	"testing"
 	"xyz/test/helloworld/encryption"
 )
@@ -353,3 +355,459 @@ func TestDecryptObjectErrorHandling(t *testing.T) {
 		t.Error("Expected error for invalid JSON, but got nil")
 	}
 }
+
+// TestSM4Modes checks every block mode against OpenSSL:
+//
+//	printf '%s' <plaintext> | openssl enc -sm4-<mode> -K <key> -iv <iv> | xxd -p
+func TestSM4Modes(t *testing.T) {
+	keyHex := "0123456789ABCDEFFEDCBA9876543210"
+	ivHex := "000102030405060708090A0B0C0D0E0F"
+	plaintext := "Hello, SM4 block modes! 0123456789"
+
+	cases := []struct {
+		mode     encryption.SM4Mode
+		expected string
+	}{
+		{encryption.ModeCBC, "7ec5cd97cf6ca999f115a249e8b6ae14f7f9e82092be9a35703e46ddc027023d1cf0ccef29d9d576bd8b65db215ada09"},
+		{encryption.ModeECB, "6b9c4097aee3ec1dc6231796cacd9db4813bb69d822dc300ae48cf821c752f1e4f889aaeef879023c302bb1ab8b21adb"},
+		{encryption.ModeCTR, "4efdf00d528a48fe67b9d7e08dc79a014f6a622f25d0dd21eaa021d6b5359b2d24e3"},
+		{encryption.ModeCFB, "4efdf00d528a48fe67b9d7e08dc79a016329b8e97986adbc762dd994ec0cf331527f"},
+		{encryption.ModeOFB, "4efdf00d528a48fe67b9d7e08dc79a01d3822d28d2f07b5d516edea7e916de17637e"},
+	}
+	for _, c := range cases {
+		sm4, err := encryption.FromHex(keyHex, ivHex, encryption.WithMode(c.mode), encryption.WithFixedIV())
+		if err != nil {
+			t.Fatalf("%v: Failed to create SM4 instance: %v", c.mode, err)
+		}
+		if sm4.Mode() != c.mode {
+			t.Errorf("Mode mismatch. Expected: %v, Got: %v", c.mode, sm4.Mode())
+		}
+
+		ciphertext, err := sm4.Encrypt2Hex(plaintext)
+		if err != nil {
+			t.Fatalf("%v: Encryption failed: %v", c.mode, err)
+		}
+		if ciphertext != c.expected {
+			t.Errorf("%v: Ciphertext mismatch. Expected: %s, Got: %s", c.mode, c.expected, ciphertext)
+		}
+
+		decrypted, err := sm4.DecryptHex(c.expected)
+		if err != nil {
+			t.Fatalf("%v: Decryption failed: %v", c.mode, err)
+		}
+		if string(decrypted) != plaintext {
+			t.Errorf("%v: Decrypted text mismatch. Expected: %s, Got: %s", c.mode, plaintext, decrypted)
+		}
+	}
+}
+
+func TestSM4StreamModesNoPadding(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+	iv, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+
+	for _, mode := range []encryption.SM4Mode{encryption.ModeCTR, encryption.ModeCFB, encryption.ModeOFB} {
+		sm4, err := encryption.NewSM4(key, iv, encryption.WithMode(mode), encryption.WithFixedIV())
+		if err != nil {
+			t.Fatalf("%v: Failed to create SM4 instance: %v", mode, err)
+		}
+		for _, plaintext := range []string{"", "a", "0123456789ABCDEF"} {
+			ciphertext, err := sm4.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("%v: Encryption failed: %v", mode, err)
+			}
+			if len(ciphertext) != len(plaintext) {
+				t.Errorf("%v: Stream mode ciphertext should be %d bytes, got %d", mode, len(plaintext), len(ciphertext))
+			}
+			decrypted, err := sm4.Decrypt(ciphertext)
+			if err != nil {
+				t.Fatalf("%v: Decryption failed: %v", mode, err)
+			}
+			if string(decrypted) != plaintext {
+				t.Errorf("%v: Decrypted text mismatch. Expected: %q, Got: %q", mode, plaintext, decrypted)
+			}
+		}
+	}
+
+	// ECB does not use the IV
+	ecb, err := encryption.NewSM4(key, nil, encryption.WithMode(encryption.ModeECB))
+	if err != nil {
+		t.Fatalf("Failed to create SM4-ECB instance: %v", err)
+	}
+	ciphertext, err := ecb.Encrypt("6222020200112233")
+	if err != nil {
+		t.Fatalf("ECB encryption failed: %v", err)
+	}
+	paddingBlock, err := ecb.Encrypt("")
+	if err != nil {
+		t.Fatalf("ECB encryption failed: %v", err)
+	}
+	// A full padding block is appended and encrypted independently
+	if len(ciphertext) != 32 || !bytes.Equal(ciphertext[16:], paddingBlock) {
+		t.Errorf("ECB should pad a full block and encrypt each block independently")
+	}
+
+	if _, err := encryption.NewSM4(key, iv, encryption.WithMode(encryption.SM4Mode(99))); err == nil {
+		t.Error("Expected error for unsupported mode")
+	}
+}
+
+func TestSM4RandomIV(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+	plaintext := "Hello, SM4 random IV!"
+
+	for _, mode := range []encryption.SM4Mode{encryption.ModeCBC, encryption.ModeCTR, encryption.ModeCFB, encryption.ModeOFB} {
+		sm4, err := encryption.NewSM4(key, nil, encryption.WithMode(mode), encryption.WithRandomIV())
+		if err != nil {
+			t.Fatalf("%v: Failed to create SM4 instance: %v", mode, err)
+		}
+		first, err := sm4.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("%v: Encryption failed: %v", mode, err)
+		}
+		second, err := sm4.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("%v: Encryption failed: %v", mode, err)
+		}
+		if bytes.Equal(first, second) {
+			t.Errorf("%v: Identical plaintexts should give different ciphertexts", mode)
+		}
+
+		// The prefix is the IV: a fixed-IV instance decrypts the remainder
+		fixed, err := encryption.NewSM4(key, first[:16], encryption.WithMode(mode), encryption.WithFixedIV())
+		if err != nil {
+			t.Fatalf("%v: Failed to create SM4 instance: %v", mode, err)
+		}
+		decrypted, err := fixed.Decrypt(append([]byte(nil), first[16:]...))
+		if err != nil || string(decrypted) != plaintext {
+			t.Errorf("%v: Fixed-IV decryption of IV-prefixed ciphertext failed: %v %q", mode, err, decrypted)
+		}
+
+		decrypted, err = sm4.Decrypt(second)
+		if err != nil {
+			t.Fatalf("%v: Decryption failed: %v", mode, err)
+		}
+		if string(decrypted) != plaintext {
+			t.Errorf("%v: Decrypted text mismatch. Expected: %s, Got: %s", mode, plaintext, decrypted)
+		}
+	}
+
+	sm4, err := encryption.FromHex("0123456789ABCDEFFEDCBA9876543210", "", encryption.WithRandomIV())
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	base64Ciphertext, err := sm4.Encrypt2Base64(plaintext)
+	if err != nil {
+		t.Fatalf("Base64 encryption failed: %v", err)
+	}
+	decrypted, err := sm4.DecryptBase64(base64Ciphertext)
+	if err != nil || string(decrypted) != plaintext {
+		t.Errorf("Base64 round trip failed: %v %q", err, decrypted)
+	}
+
+	if _, err := sm4.Decrypt(make([]byte, 15)); !errors.Is(err, encryption.ErrSM4InvalidCiphertext) {
+		t.Errorf("Expected ErrSM4InvalidCiphertext for missing IV, got: %v", err)
+	}
+	if _, err := encryption.NewSM4(key, nil, encryption.WithMode(encryption.ModeECB), encryption.WithRandomIV()); err == nil {
+		t.Error("Expected error for random IV with ECB")
+	}
+}
+
+func TestUnpadPKCS7(t *testing.T) {
+	cases := []struct {
+		name  string
+		input []byte
+		valid bool
+	}{
+		{"OnePaddingByte", append(bytes.Repeat([]byte{'a'}, 15), 0x01), true},
+		{"FullPaddingBlock", bytes.Repeat([]byte{0x10}, 16), true},
+		{"FivePaddingBytes", append([]byte("test data00"), 0x05, 0x05, 0x05, 0x05, 0x05), true},
+		{"ZeroPadding", append(bytes.Repeat([]byte{'a'}, 15), 0x00), false},
+		{"PaddingTooLarge", append(bytes.Repeat([]byte{'a'}, 15), 0x11), false},
+		{"InconsistentPadding", append([]byte("test data00"), 0x05, 0x05, 0x04, 0x05, 0x05), false},
+		{"NotBlockAligned", append([]byte("test data"), 0x01), false},
+		{"Empty", []byte{}, false},
+	}
+	for _, c := range cases {
+		result, err := encryption.UnpadPKCS7(c.input, 16)
+		if c.valid {
+			if err != nil {
+				t.Errorf("%s: UnpadPKCS7 failed: %v", c.name, err)
+			} else if padNum := int(c.input[len(c.input)-1]); !bytes.Equal(result, c.input[:len(c.input)-padNum]) {
+				t.Errorf("%s: Unpadded data mismatch: %v", c.name, result)
+			}
+		} else if !errors.Is(err, encryption.ErrInvalidPadding) {
+			t.Errorf("%s: Expected ErrInvalidPadding, got: %v", c.name, err)
+		}
+	}
+}
+
+func TestSM4DecryptValidation(t *testing.T) {
+	keyHex := "0123456789ABCDEFFEDCBA9876543210"
+	ivHex := "00000000000000000000000000000000"
+
+	for _, mode := range []encryption.SM4Mode{encryption.ModeCBC, encryption.ModeECB} {
+		sm4, err := encryption.FromHex(keyHex, ivHex, encryption.WithMode(mode))
+		if err != nil {
+			t.Fatalf("%v: Failed to create SM4 instance: %v", mode, err)
+		}
+
+		// Not a multiple of the block size must not panic
+		for _, n := range []int{0, 1, 15, 17} {
+			if _, err := sm4.Decrypt(make([]byte, n)); !errors.Is(err, encryption.ErrSM4InvalidCiphertext) {
+				t.Errorf("%v: Expected ErrSM4InvalidCiphertext for %d bytes, got: %v", mode, n, err)
+			}
+		}
+
+		ciphertext, err := sm4.Encrypt("Hello, SM4 padding!")
+		if err != nil {
+			t.Fatalf("%v: Encryption failed: %v", mode, err)
+		}
+		wrongKey, err := encryption.FromHex("FEDCBA98765432100123456789ABCDEF", ivHex, encryption.WithMode(mode))
+		if err != nil {
+			t.Fatalf("%v: Failed to create SM4 instance: %v", mode, err)
+		}
+		if _, err := wrongKey.Decrypt(ciphertext); !errors.Is(err, encryption.ErrInvalidPadding) {
+			t.Errorf("%v: Expected ErrInvalidPadding for wrong key, got: %v", mode, err)
+		}
+	}
+
+	sm4, err := encryption.FromHex(keyHex, ivHex)
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	if _, err := sm4.DecryptHex("00112233"); !errors.Is(err, encryption.ErrSM4InvalidCiphertext) {
+		t.Errorf("Expected ErrSM4InvalidCiphertext from DecryptHex, got: %v", err)
+	}
+}
+
+func TestSM4DecryptDoesNotMutateInput(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+	iv, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	plaintext := "Hello, SM4 retries!"
+
+	for _, mode := range []encryption.SM4Mode{encryption.ModeCBC, encryption.ModeECB, encryption.ModeCTR, encryption.ModeCFB, encryption.ModeOFB} {
+		sm4, err := encryption.NewSM4(key, iv, encryption.WithMode(mode), encryption.WithFixedIV())
+		if err != nil {
+			t.Fatalf("%v: Failed to create SM4 instance: %v", mode, err)
+		}
+		ciphertext, err := sm4.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("%v: Encryption failed: %v", mode, err)
+		}
+		original := bytes.Clone(ciphertext)
+
+		// Decrypting the same buffer twice must give the same result
+		for i := 0; i < 2; i++ {
+			decrypted, err := sm4.Decrypt(ciphertext)
+			if err != nil {
+				t.Fatalf("%v: Decryption %d failed: %v", mode, i, err)
+			}
+			if string(decrypted) != plaintext {
+				t.Errorf("%v: Decryption %d mismatch. Expected: %s, Got: %s", mode, i, plaintext, decrypted)
+			}
+		}
+		if !bytes.Equal(ciphertext, original) {
+			t.Errorf("%v: Decrypt modified the ciphertext buffer", mode)
+		}
+
+		decrypted, err := sm4.DecryptInPlace(ciphertext)
+		if err != nil {
+			t.Fatalf("%v: DecryptInPlace failed: %v", mode, err)
+		}
+		if string(decrypted) != plaintext {
+			t.Errorf("%v: DecryptInPlace mismatch. Expected: %s, Got: %s", mode, plaintext, decrypted)
+		}
+		if &decrypted[0] != &ciphertext[0] {
+			t.Errorf("%v: DecryptInPlace should reuse the ciphertext buffer", mode)
+		}
+	}
+}
+
+func TestSM4KeyAndIVValidation(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+	iv, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+
+	for _, n := range []int{0, 15, 17, 32} {
+		if _, err := encryption.NewSM4(make([]byte, n), iv); !errors.Is(err, encryption.ErrSM4InvalidKey) {
+			t.Errorf("Expected ErrSM4InvalidKey for %d-byte key, got: %v", n, err)
+		}
+		if _, err := encryption.NewSM4AEAD(make([]byte, n), encryption.AEADGCM); !errors.Is(err, encryption.ErrSM4InvalidKey) {
+			t.Errorf("Expected ErrSM4InvalidKey for %d-byte AEAD key, got: %v", n, err)
+		}
+	}
+	for _, mode := range []encryption.SM4Mode{encryption.ModeCBC, encryption.ModeCTR, encryption.ModeCFB, encryption.ModeOFB} {
+		for _, n := range []int{0, 8, 32} {
+			if _, err := encryption.NewSM4(key, make([]byte, n), encryption.WithMode(mode), encryption.WithFixedIV()); !errors.Is(err, encryption.ErrSM4InvalidIV) {
+				t.Errorf("%v: Expected ErrSM4InvalidIV for %d-byte IV, got: %v", mode, n, err)
+			}
+		}
+	}
+	if _, err := encryption.FromHex("0123456789ABCDEF", "000102030405060708090A0B0C0D0E0F"); !errors.Is(err, encryption.ErrSM4InvalidKey) {
+		t.Errorf("Expected ErrSM4InvalidKey from FromHex, got: %v", err)
+	}
+	if _, err := encryption.FromBase64("ASNFZ4mrze/93LqYdlQyEA==", "AAAAAAAA"); !errors.Is(err, encryption.ErrSM4InvalidIV) {
+		t.Errorf("Expected ErrSM4InvalidIV from FromBase64, got: %v", err)
+	}
+
+	// ECB and random-IV instances do not need an IV
+	if _, err := encryption.NewSM4(key, nil, encryption.WithMode(encryption.ModeECB)); err != nil {
+		t.Errorf("ECB without IV failed: %v", err)
+	}
+	if _, err := encryption.NewSM4(key, nil, encryption.WithRandomIV()); err != nil {
+		t.Errorf("Random IV without IV failed: %v", err)
+	}
+
+	// Stream modes refuse a fixed IV unless the caller opts in, since reuse leaks the keystream
+	for _, mode := range []encryption.SM4Mode{encryption.ModeCTR, encryption.ModeCFB, encryption.ModeOFB} {
+		if _, err := encryption.NewSM4(key, iv, encryption.WithMode(mode)); !errors.Is(err, encryption.ErrSM4InvalidIV) {
+			t.Errorf("%v: Expected ErrSM4InvalidIV for fixed IV without opt-in, got: %v", mode, err)
+		}
+		if _, err := encryption.NewSM4(key, nil, encryption.WithMode(mode), encryption.WithRandomIV()); err != nil {
+			t.Errorf("%v: Random IV without IV failed: %v", mode, err)
+		}
+		if _, err := encryption.NewSM4(key, iv, encryption.WithMode(mode), encryption.WithRandomIV(), encryption.WithFixedIV()); err == nil {
+			t.Errorf("%v: Expected error when combining WithRandomIV and WithFixedIV", mode)
+		}
+	}
+	if _, err := encryption.NewSM4(key, iv, encryption.WithFixedIV()); err != nil {
+		t.Errorf("CBC with WithFixedIV failed: %v", err)
+	}
+
+	// Later changes to the caller's IV buffer do not affect the instance
+	sm4, err := encryption.NewSM4(key, iv)
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	ciphertext, err := sm4.Encrypt("Hello, SM4!")
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	iv[0] ^= 0xff
+	again, err := sm4.Encrypt("Hello, SM4!")
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	if !bytes.Equal(ciphertext, again) {
+		t.Error("SM4 instance should keep its own copy of the IV")
+	}
+}
+
+func TestSM4ConcurrentUse(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+	iv, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	sm4, err := encryption.NewSM4(key, iv)
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	aead, err := encryption.NewSM4AEAD(key, encryption.AEADGCM)
+	if err != nil {
+		t.Fatalf("Failed to create SM4 AEAD instance: %v", err)
+	}
+	plaintext := "shared across goroutines: 0123456789ABCDEF"
+	expected, err := sm4.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ciphertext, err := sm4.Encrypt(plaintext)
+				if err != nil || !bytes.Equal(ciphertext, expected) {
+					errs <- errors.New("concurrent SM4 encryption produced a wrong ciphertext")
+					return
+				}
+				sealed, err := aead.Encrypt(plaintext, nil)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if opened, err := aead.Decrypt(sealed, nil); err != nil || string(opened) != plaintext {
+					errs <- errors.New("concurrent SM4-GCM round trip failed")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestSM4EncryptBytes(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+	iv, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	plaintext := []byte{0x00, 0xff, 0xfe, 0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}
+
+	for _, mode := range []encryption.SM4Mode{encryption.ModeCBC, encryption.ModeECB, encryption.ModeCTR} {
+		sm4, err := encryption.NewSM4(key, iv, encryption.WithMode(mode), encryption.WithFixedIV())
+		if err != nil {
+			t.Fatalf("%v: Failed to create SM4 instance: %v", mode, err)
+		}
+
+		// Same ciphertext as the string API
+		ciphertext, err := sm4.EncryptBytes(plaintext)
+		if err != nil {
+			t.Fatalf("%v: EncryptBytes failed: %v", mode, err)
+		}
+		expected, err := sm4.Encrypt(string(plaintext))
+		if err != nil {
+			t.Fatalf("%v: Encryption failed: %v", mode, err)
+		}
+		if !bytes.Equal(ciphertext, expected) {
+			t.Errorf("%v: EncryptBytes differs from Encrypt", mode)
+		}
+
+		for _, encoding := range []encryption.Encoding{encryption.EncodingHex, encryption.EncodingBase64, encryption.EncodingBase64URL, encryption.EncodingRaw} {
+			encoded, err := sm4.EncryptEncoded(plaintext, encoding)
+			if err != nil {
+				t.Fatalf("%v/%v: EncryptEncoded failed: %v", mode, encoding, err)
+			}
+			decrypted, err := sm4.DecryptEncoded(encoded, encoding)
+			if err != nil {
+				t.Fatalf("%v/%v: DecryptEncoded failed: %v", mode, encoding, err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("%v/%v: Decrypted data mismatch", mode, encoding)
+			}
+		}
+	}
+
+	sm4, err := encryption.NewSM4(key, iv)
+	if err != nil {
+		t.Fatalf("Failed to create SM4 instance: %v", err)
+	}
+	encoded, err := sm4.EncryptEncoded(plaintext, encryption.EncodingBase64)
+	if err != nil {
+		t.Fatalf("EncryptEncoded failed: %v", err)
+	}
+	if base64Text, _ := sm4.Encrypt2Base64(string(plaintext)); encoded != base64Text {
+		t.Error("EncryptEncoded with EncodingBase64 differs from Encrypt2Base64")
+	}
+
+	// Spare capacity in the caller's slice is not used for padding
+	buf := make([]byte, 20, 64)
+	copy(buf, "binary plaintext....")
+	spare := buf[:cap(buf)]
+	for i := len(buf); i < len(spare); i++ {
+		spare[i] = 0xaa
+	}
+	if _, err := sm4.EncryptBytes(buf); err != nil {
+		t.Fatalf("EncryptBytes failed: %v", err)
+	}
+	for i := len(buf); i < len(spare); i++ {
+		if spare[i] != 0xaa {
+			t.Fatal("EncryptBytes wrote into the caller's spare capacity")
+		}
+	}
+
+	if _, err := sm4.DecryptEncoded("00", encryption.Encoding(99)); !errors.Is(err, encryption.ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding, got: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+#!/usr/bin/env python3
+"""Build gmt0010_openssl_signed.der: a PKCS#7 SignedData with authenticated attributes.
+
+OpenSSL 3.0 cannot emit SM2 PKCS#7/CMS, so the DER is assembled here while every
+cryptographic value comes from the openssl CLI: the SM3 messageDigest from
+`openssl dgst -sm3` and the SM2 signature over the attribute SET from
+`openssl pkeyutl -sign -rawin -digest sm3 -pkeyopt distid:1234567812345678`.
+The layout follows what Tongsuo/GmSSL emit: PKCS#7 content-type OIDs,
+SM3 digest, SM2 signature and contentType/signingTime/messageDigest attributes.
+
+Run from test/testdata: python3 gmt0010_openssl_signed.py
+"""
+import subprocess
+
+CONTENT = b"Hello, GM/T 0010 from OpenSSL!"
+SIGNING_TIME = b"260101000000Z"
+
+
+def tlv(tag, body):
+    n = len(body)
+    if n < 0x80:
+        length = bytes([n])
+    else:
+        raw = n.to_bytes((n.bit_length() + 7) // 8, "big")
+        length = bytes([0x80 | len(raw)]) + raw
+    return bytes([tag]) + length + body
+
+
+def oid(dotted):
+    parts = [int(p) for p in dotted.split(".")]
+    body = bytes([parts[0] * 40 + parts[1]])
+    for p in parts[2:]:
+        chunk = [p & 0x7F]
+        p >>= 7
+        while p:
+            chunk.append(0x80 | (p & 0x7F))
+            p >>= 7
+        body += bytes(reversed(chunk))
+    return tlv(0x06, body)
+
+
+def children(der):
+    """Split the body of a constructed TLV into its child TLVs."""
+    body, out = read(der)[1], []
+    while body:
+        header = 2 if body[1] < 0x80 else 2 + (body[1] & 0x7F)
+        _, inner = read(body)
+        out.append(body[: header + len(inner)])
+        body = body[header + len(inner):]
+    return out
+
+
+def read(der):
+    n, i = der[1], 2
+    if n & 0x80:
+        i = 2 + (n & 0x7F)
+        n = int.from_bytes(der[2:i], "big")
+    return der[0], der[i : i + n]
+
+
+def openssl(*args, data=None):
+    return subprocess.run(["openssl", *args], input=data, capture_output=True, check=True).stdout
+
+
+cert = openssl("x509", "-in", "sm2_cert.pem", "-outform", "DER")
+tbs = children(children(cert)[0])
+serial, issuer = tbs[1], tbs[3]
+
+data_oid = oid("1.2.840.113549.1.7.1")
+sm3 = tlv(0x30, oid("1.2.156.10197.1.401"))
+digest = openssl("dgst", "-sm3", "-binary", data=CONTENT)
+
+attributes = b"".join(
+    sorted(
+        [
+            tlv(0x30, oid("1.2.840.113549.1.9.3") + tlv(0x31, data_oid)),
+            tlv(0x30, oid("1.2.840.113549.1.9.5") + tlv(0x31, tlv(0x17, SIGNING_TIME))),
+            tlv(0x30, oid("1.2.840.113549.1.9.4") + tlv(0x31, tlv(0x04, digest))),
+        ]
+    )
+)
+with open("attrs.tmp", "wb") as f:
+    f.write(tlv(0x31, attributes))
+signature = openssl(
+    "pkeyutl", "-sign", "-inkey", "sm2_pkcs8.pem", "-rawin", "-digest", "sm3",
+    "-pkeyopt", "distid:1234567812345678", "-in", "attrs.tmp",
+)
+subprocess.run(["rm", "attrs.tmp"], check=True)
+
+signer_info = tlv(
+    0x30,
+    tlv(0x02, b"\x01")
+    + tlv(0x30, issuer + serial)
+    + sm3
+    + tlv(0xA0, attributes)
+    + tlv(0x30, oid("1.2.156.10197.1.301.1"))
+    + tlv(0x04, signature),
+)
+signed_data = tlv(
+    0x30,
+    tlv(0x02, b"\x01")
+    + tlv(0x31, sm3)
+    + tlv(0x30, data_oid + tlv(0xA0, tlv(0x04, CONTENT)))
+    + tlv(0xA0, cert)
+    + tlv(0x31, signer_info),
+)
+with open("gmt0010_openssl_signed.der", "wb") as f:
+    f.write(tlv(0x30, oid("1.2.840.113549.1.7.2") + tlv(0xA0, signed_data)))